@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/goccy/go-json"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/services/state"
+)
+
+// A minipool's attestation record for the interval, as the Smoothing Pool calculation counted it
+type minipoolAttestationDetail struct {
+	Minipool                common.Address `json:"minipool"`
+	Pubkey                  string         `json:"pubkey"`
+	InPerformanceFile       bool           `json:"inPerformanceFile"`
+	SuccessfulAttestations  uint64         `json:"successfulAttestations"`
+	MissedAttestations      uint64         `json:"missedAttestations"`
+	MissingAttestationSlots []uint64       `json:"missingAttestationSlots"`
+	EthEarned               string         `json:"ethEarned"`
+}
+
+// Prints the attestations counted for each of a node's minipools as JSON, for resolving disputes over Smoothing Pool rewards.
+// The performance file only lists the missed slots; every other duty in the minipool's eligible period was attested.
+// Minipools that aren't in the performance file weren't eligible for the Smoothing Pool during the interval.
+func (g *treeGenerator) printAttestationDetail(rewardsFile rprewards.IRewardsFile, networkState *state.NetworkState, node common.Address) error {
+	minipools := networkState.MinipoolDetailsByNode[node]
+	if len(minipools) == 0 {
+		return fmt.Errorf("node %s has no minipools at slot %d", node.Hex(), networkState.BeaconSlotNumber)
+	}
+	perfFile := rewardsFile.GetMinipoolPerformanceFile()
+
+	details := make([]minipoolAttestationDetail, 0, len(minipools))
+	for _, mpd := range minipools {
+		detail := minipoolAttestationDetail{
+			Minipool:                mpd.MinipoolAddress,
+			Pubkey:                  mpd.Pubkey.Hex(),
+			MissingAttestationSlots: []uint64{},
+			EthEarned:               "0",
+		}
+		if perf, exists := perfFile.GetSmoothingPoolPerformance(mpd.MinipoolAddress); exists {
+			detail.InPerformanceFile = true
+			detail.SuccessfulAttestations = perf.GetSuccessfulAttestationCount()
+			detail.MissedAttestations = perf.GetMissedAttestationCount()
+			detail.MissingAttestationSlots = append(detail.MissingAttestationSlots, perf.GetMissingAttestationSlots()...)
+			sort.Slice(detail.MissingAttestationSlots, func(i, j int) bool {
+				return detail.MissingAttestationSlots[i] < detail.MissingAttestationSlots[j]
+			})
+			detail.EthEarned = perf.GetEthEarned().String()
+		}
+		details = append(details, detail)
+	}
+	sort.Slice(details, func(i, j int) bool {
+		return bytes.Compare(details[i].Minipool.Bytes(), details[j].Minipool.Bytes()) < 0
+	})
+
+	output, err := json.MarshalIndent(details, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error serializing attestation detail into JSON: %w", err)
+	}
+	g.log.Printlnf("Attestation detail for the %d minipool(s) of node %s:", len(details), node.Hex())
+	fmt.Println(string(output))
+	return nil
+}
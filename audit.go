@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goccy/go-json"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// One line of the --audit-log file, describing a single tree generation.
+// A nil record is valid and records nothing.
+type auditRecord struct {
+	RunId           string    `json:"runId"`
+	Timestamp       time.Time `json:"timestamp"`
+	Interval        *uint64   `json:"interval,omitempty"`
+	Ruleset         uint64    `json:"ruleset,omitempty"`
+	MerkleRoot      string    `json:"merkleRoot,omitempty"`
+	CanonicalMatch  *bool     `json:"canonicalMatch,omitempty"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	OutputPaths     []string  `json:"outputPaths"`
+	Error           string    `json:"error,omitempty"`
+
+	// Only reported by --summary-only
+	totalSmoothingPoolEth      *rprewards.QuotedBigInt
+	poolStakerSmoothingPoolEth *rprewards.QuotedBigInt
+	rewardSplit                *rewardSplit
+}
+
+// Sets the interval being generated
+func (r *auditRecord) setInterval(index uint64) {
+	if r == nil {
+		return
+	}
+	r.Interval = &index
+}
+
+// Sets the ruleset and root of the generated tree
+func (r *auditRecord) setTree(ruleset uint64, root string) {
+	if r == nil {
+		return
+	}
+	r.Ruleset = ruleset
+	r.MerkleRoot = root
+}
+
+// Sets the Smoothing Pool totals of the generated tree
+func (r *auditRecord) setTotals(totals *rprewards.TotalRewards) {
+	if r == nil || totals == nil {
+		return
+	}
+	r.totalSmoothingPoolEth = totals.TotalSmoothingPoolEth
+	r.poolStakerSmoothingPoolEth = totals.PoolStakerSmoothingPoolEth
+}
+
+// Sets the reward split of the generated tree
+func (r *auditRecord) setRewardSplit(split *rewardSplit) {
+	if r == nil {
+		return
+	}
+	r.rewardSplit = split
+}
+
+// Sets whether the generated root matched the canonical one
+func (r *auditRecord) setCanonicalMatch(match bool) {
+	if r == nil {
+		return
+	}
+	r.CanonicalMatch = &match
+}
+
+// Adds a file that was written
+func (r *auditRecord) addOutput(path string) {
+	if r == nil {
+		return
+	}
+	r.OutputPaths = append(r.OutputPaths, path)
+}
+
+// Completes the current audit record with the run's outcome, prints it if --summary-only is set, and appends it to the audit log.
+// Returns the run's error, or the audit log error if the run itself succeeded.
+func (g *treeGenerator) finishAudit(start time.Time, runErr error) error {
+	record := g.audit
+	g.audit = nil
+	record.DurationSeconds = time.Since(start).Seconds()
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+
+	if g.summaryOnly {
+		g.printSummary(record)
+	}
+	if g.auditLog == "" {
+		return runErr
+	}
+
+	err := appendAuditRecord(g.auditLog, record)
+	if err == nil {
+		return runErr
+	}
+	if runErr != nil {
+		g.errLog.Printlnf("error writing to the audit log: %s", err.Error())
+		return runErr
+	}
+	return fmt.Errorf("error writing to the audit log: %w", err)
+}
+
+// Appends a record to the audit log as a single line, creating the file if it doesn't exist.
+// The line is written with one append-mode write so concurrent runs don't interleave.
+func appendAuditRecord(path string, record *auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error serializing audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+)
+
+// The time spent in a single phase of a treegen run
+type phaseDuration struct {
+	name     string
+	duration time.Duration
+}
+
+// Collects the durations of each phase of a treegen run for --benchmark.
+// A nil timer is valid and records nothing.
+type phaseTimer struct {
+	phases []phaseDuration
+}
+
+// Adds the time elapsed since start to the given phase
+func (t *phaseTimer) record(name string, start time.Time) {
+	if t == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	for i := range t.phases {
+		if t.phases[i].name == name {
+			t.phases[i].duration += elapsed
+			return
+		}
+	}
+	t.phases = append(t.phases, phaseDuration{
+		name:     name,
+		duration: elapsed,
+	})
+}
+
+// Prints a breakdown of the recorded phases
+func (t *phaseTimer) print(logger *log.ColorLogger) {
+	if t == nil {
+		return
+	}
+
+	total := time.Duration(0)
+	logger.Println()
+	logger.Println("=== Benchmark ===")
+	for _, phase := range t.phases {
+		logger.Printlnf("%-22s %s", phase.name+":", phase.duration.Round(time.Millisecond))
+		total += phase.duration
+	}
+	logger.Printlnf("%-22s %s", "Total:", total.Round(time.Millisecond))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+)
+
+// What a slot resolved to when treegen looked it up on the BN
+type blockMapEntry struct {
+	Slot           uint64    `json:"slot"`
+	SlotTime       time.Time `json:"slotTime"`
+	Proposed       bool      `json:"proposed"`
+	ExecutionBlock uint64    `json:"executionBlock,omitempty"`
+}
+
+// Every slot treegen looked up during a run, for --block-map
+type blockMap struct {
+	lock    sync.Mutex
+	entries map[uint64]blockMapEntry
+}
+
+// A BN client that records each beacon block it returns, including missed slots, in a block map.
+// Only treegen's own lookups go through it; the state manager has its own client.
+type recordingBeaconClient struct {
+	beacon.Client
+	blocks *blockMap
+	g      *treeGenerator
+}
+
+func (c *recordingBeaconClient) GetBeaconBlock(blockId string) (beacon.BeaconBlock, bool, error) {
+	block, exists, err := c.Client.GetBeaconBlock(blockId)
+	if err != nil {
+		return block, exists, err
+	}
+
+	// Missed slots come back empty, so their slot is taken from the ID that was asked for
+	slot := block.Slot
+	if !exists {
+		slot, err = strconv.ParseUint(blockId, 10, 64)
+		if err != nil {
+			return block, exists, nil
+		}
+	}
+	c.blocks.lock.Lock()
+	c.blocks.entries[slot] = blockMapEntry{
+		Slot:           slot,
+		SlotTime:       c.g.slotToTime(slot),
+		Proposed:       exists,
+		ExecutionBlock: block.ExecutionBlockNumber,
+	}
+	c.blocks.lock.Unlock()
+	return block, exists, nil
+}
+
+// Routes the generator's BN lookups through a block map recorder
+func (g *treeGenerator) recordBlockMap() *blockMap {
+	blocks := &blockMap{entries: map[uint64]blockMapEntry{}}
+	g.bn = &recordingBeaconClient{Client: g.bn, blocks: blocks, g: g}
+	return blocks
+}
+
+// Saves the recorded slots, ordered by slot. The format follows the file extension: .csv writes CSV, anything else writes JSON.
+func (g *treeGenerator) writeBlockMap(blocks *blockMap, path string) error {
+	blocks.lock.Lock()
+	entries := make([]blockMapEntry, 0, len(blocks.entries))
+	for _, entry := range blocks.entries {
+		entries = append(entries, entry)
+	}
+	blocks.lock.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Slot < entries[j].Slot
+	})
+
+	var data []byte
+	var err error
+	if filepath.Ext(path) == ".csv" {
+		buffer := &bytes.Buffer{}
+		writer := csv.NewWriter(buffer)
+		err = writer.Write([]string{"slot", "slotTime", "proposed", "executionBlock"})
+		if err != nil {
+			return fmt.Errorf("error writing CSV header: %w", err)
+		}
+		for _, entry := range entries {
+			err = writer.Write([]string{fmt.Sprint(entry.Slot), entry.SlotTime.UTC().Format(time.RFC3339), strconv.FormatBool(entry.Proposed), fmt.Sprint(entry.ExecutionBlock)})
+			if err != nil {
+				return fmt.Errorf("error writing CSV row for slot %d: %w", entry.Slot, err)
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("error writing CSV: %w", err)
+		}
+		data = buffer.Bytes()
+	} else {
+		data, err = g.serializeJson(entries)
+		if err != nil {
+			return fmt.Errorf("error serializing block map into JSON: %w", err)
+		}
+	}
+
+	err = writeFileAtomic(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("error saving block map to %s: %w", path, err)
+	}
+	g.log.Printlnf("Saved the %d slots looked up during the run to %s", len(entries), path)
+	return nil
+}
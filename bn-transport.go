@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// An HTTP transport that bounds the number of BN requests in flight at once.
+// The state manager's own concurrency is fixed inside the Smartnode, but its BN client always goes through
+// http.DefaultClient, so this is where the validator status fetch can be throttled.
+type limitedTransport struct {
+	http.RoundTripper
+	sem chan struct{}
+}
+
+// Creates a transport that allows at most maxRequests concurrent requests through the given one
+func newLimitedTransport(transport http.RoundTripper, maxRequests int) *limitedTransport {
+	return &limitedTransport{
+		RoundTripper: transport,
+		sem:          make(chan struct{}, maxRequests),
+	}
+}
+
+// Holds a request slot until the response body has been read and closed
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.sem <- struct{}{}
+	var once sync.Once
+	release := func() { once.Do(func() { <-t.sem }) }
+	response, err := t.RoundTripper.RoundTrip(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release}
+	return response, nil
+}
+
+// A response body that frees its request slot when closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// An HTTP transport that identifies treegen to the BN with a User-Agent header
+type userAgentTransport struct {
+	http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if t.userAgent == "" {
+		return t.RoundTripper.RoundTrip(request)
+	}
+	// RoundTrippers must not modify the caller's request
+	request = request.Clone(request.Context())
+	request.Header.Set("User-Agent", t.userAgent)
+	return t.RoundTripper.RoundTrip(request)
+}
+
+// The Unix socket that a BN endpoint refers to, given as an absolute path or a unix:// URL; empty for a TCP endpoint
+func bnSocketPath(bnUrl string) string {
+	if strings.HasPrefix(bnUrl, "unix://") {
+		return strings.TrimPrefix(bnUrl, "unix://")
+	}
+	if strings.HasPrefix(bnUrl, "/") {
+		return bnUrl
+	}
+	return ""
+}
+
+// Creates a transport that sends every request over the given Unix socket, whatever host the URL names
+func newUnixSocketTransport(path string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", path)
+	}
+	return transport
+}
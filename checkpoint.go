@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+)
+
+// Gets the block at the Nth most recent finalized checkpoint, as seen from the BN's head.
+// A checkpoint is the block at the start of its epoch, or the last one before it if that slot was missed.
+func (g *treeGenerator) checkpointBlock(checkpointsBack uint64) (*beacon.BeaconBlock, error) {
+	head, err := g.bn.GetBeaconHead()
+	if err != nil {
+		return nil, fmt.Errorf("unable to query beacon head: %w", err)
+	}
+	if checkpointsBack > head.FinalizedEpoch {
+		return nil, fmt.Errorf("the latest finalized epoch is %d, so there is no checkpoint %d epochs before it", head.FinalizedEpoch, checkpointsBack)
+	}
+	epoch := head.FinalizedEpoch - checkpointsBack
+
+	slot := epoch * g.beaconConfig.SlotsPerEpoch
+	block, err := g.lastBlockBeforeTime(g.slotToTime(slot))
+	if err != nil {
+		return nil, g.bnError(err, slot, "getting the checkpoint block")
+	}
+	if block == nil {
+		return nil, fmt.Errorf("unable to find the checkpoint block for epoch %d; no blocks were proposed in the epoch before slot %d", epoch, slot)
+	}
+	g.log.Printlnf("Targeting the finalized checkpoint of epoch %d (%d back from the latest), at slot %d.", epoch, checkpointsBack, block.Slot)
+	return block, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Prints the ABI-encoded calldata for claiming a node's rewards for the generated interval from the Merkle distributor,
+// so it can be sent manually. Only the node itself can submit the claim.
+func (g *treeGenerator) printClaimCalldata(rewardsFile rprewards.IRewardsFile, node common.Address) error {
+	header := rewardsFile.GetHeader()
+	info, exists := rewardsFile.GetNodeRewardsInfo(node)
+	if !exists || merkleLeaf(node, info) == nil {
+		return fmt.Errorf("node %s has no rewards in interval %d, so there is nothing to claim", node.Hex(), header.Index)
+	}
+	if info.GetRewardNetwork() != 0 {
+		return fmt.Errorf("node %s is on reward network %d; only rewards on network 0 can be claimed from the mainnet distributor", node.Hex(), info.GetRewardNetwork())
+	}
+	proof, err := info.GetMerkleProof()
+	if err != nil {
+		return fmt.Errorf("error reading the Merkle proof for node %s: %w", node.Hex(), err)
+	}
+
+	distributor, err := g.rp.GetContract("rocketMerkleDistributorMainnet", nil)
+	if err != nil {
+		return fmt.Errorf("error getting the Merkle distributor contract: %w", err)
+	}
+	rpl := big.NewInt(0).Add(&info.GetCollateralRpl().Int, &info.GetOracleDaoRpl().Int)
+	eth := &info.GetSmoothingPoolEth().Int
+	calldata, err := distributor.ABI.Pack("claim", node,
+		[]*big.Int{big.NewInt(0).SetUint64(header.Index)},
+		[]*big.Int{rpl},
+		[]*big.Int{eth},
+		[][]common.Hash{proof},
+	)
+	if err != nil {
+		return fmt.Errorf("error encoding the claim calldata: %w", err)
+	}
+
+	g.log.Printlnf("Claim for node %s in interval %d: %s RPL wei and %s ETH wei", node.Hex(), header.Index, rpl.String(), eth.String())
+	g.log.Printlnf("Send from the node address to the Merkle distributor at %s with this calldata:", distributor.Address.Hex())
+	fmt.Println(hexutil.Encode(calldata))
+	return nil
+}
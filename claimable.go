@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/goccy/go-json"
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// The rewards a withdrawal address's nodes earned in one interval
+type intervalClaimable struct {
+	index uint64
+	nodes int
+	rpl   *big.Int
+	eth   *big.Int
+}
+
+// Totals the RPL and ETH earned by every node with the given withdrawal address across a directory of rewards files.
+// Rewards files only record node addresses, so the node to withdrawal address mapping comes from a --roster export.
+func SumClaimable(c *cli.Context) error {
+	logger := log.NewColorLogger(color.FgHiWhite)
+	withdrawalAddress := c.String("claimable-for")
+	if !common.IsHexAddress(withdrawalAddress) {
+		return fmt.Errorf("%s is not a valid address", withdrawalAddress)
+	}
+	rewardsDir := c.String("rewards-dir")
+	if rewardsDir == "" {
+		return fmt.Errorf("claimable-for requires rewards-dir")
+	}
+	rosterPath := c.String("roster-file")
+	if rosterPath == "" {
+		return fmt.Errorf("claimable-for requires roster-file, since rewards files don't record withdrawal addresses")
+	}
+
+	nodes, err := loadRosterNodes(rosterPath, common.HexToAddress(withdrawalAddress))
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes in %s have the withdrawal address %s", rosterPath, withdrawalAddress)
+	}
+	logger.Printlnf("Found %d node(s) with the withdrawal address %s", len(nodes), withdrawalAddress)
+
+	paths, err := filepath.Glob(filepath.Join(rewardsDir, "rp-rewards-*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing rewards files in %s: %w", rewardsDir, err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no rewards files found in %s", rewardsDir)
+	}
+
+	intervals := make([]intervalClaimable, 0, len(paths))
+	for _, path := range paths {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		rewardsFile, err := rprewards.DeserializeRewardsFile(bytes)
+		if err != nil {
+			return fmt.Errorf("error deserializing %s: %w", path, err)
+		}
+
+		interval := intervalClaimable{
+			index: rewardsFile.GetHeader().Index,
+			rpl:   big.NewInt(0),
+			eth:   big.NewInt(0),
+		}
+		for node := range nodes {
+			info, exists := rewardsFile.GetNodeRewardsInfo(node)
+			if !exists {
+				continue
+			}
+			interval.nodes++
+			interval.rpl.Add(interval.rpl, &info.GetCollateralRpl().Int)
+			interval.rpl.Add(interval.rpl, &info.GetOracleDaoRpl().Int)
+			interval.eth.Add(interval.eth, &info.GetSmoothingPoolEth().Int)
+		}
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].index < intervals[j].index
+	})
+
+	totalRpl := big.NewInt(0)
+	totalEth := big.NewInt(0)
+	fmt.Printf("%-10s %-6s %20s %20s\n", "Interval", "Nodes", "RPL", "ETH")
+	for _, interval := range intervals {
+		fmt.Printf("%-10d %-6d %20.6f %20.6f\n", interval.index, interval.nodes, eth.WeiToEth(interval.rpl), eth.WeiToEth(interval.eth))
+		totalRpl.Add(totalRpl, interval.rpl)
+		totalEth.Add(totalEth, interval.eth)
+	}
+	fmt.Printf("%-10s %-6s %20.6f %20.6f\n", "Total", "", eth.WeiToEth(totalRpl), eth.WeiToEth(totalEth))
+	logger.Printlnf("These are the amounts earned in each file; rewards files don't record which intervals have already been claimed.")
+	return nil
+}
+
+// Loads the nodes with the given withdrawal address from a roster written by --roster, in either its JSON or CSV format
+func loadRosterNodes(path string, withdrawalAddress common.Address) (map[common.Address]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening roster %s: %w", path, err)
+	}
+	defer file.Close()
+
+	nodes := map[common.Address]bool{}
+	if filepath.Ext(path) == ".csv" {
+		rows, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("error reading roster %s: %w", path, err)
+		}
+		// Skip the header; each row is node, withdrawal address, pubkey
+		for i, row := range rows {
+			if i == 0 || len(row) < 2 {
+				continue
+			}
+			if common.HexToAddress(row[1]) == withdrawalAddress {
+				nodes[common.HexToAddress(row[0])] = true
+			}
+		}
+		return nodes, nil
+	}
+
+	var roster []rosterNode
+	if err := json.NewDecoder(file).Decode(&roster); err != nil {
+		return nil, fmt.Errorf("error parsing roster %s: %w", path, err)
+	}
+	for _, node := range roster {
+		if common.HexToAddress(node.WithdrawalAddress) == withdrawalAddress {
+			nodes[common.HexToAddress(node.NodeAddress)] = true
+		}
+	}
+	return nodes, nil
+}
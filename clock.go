@@ -0,0 +1,37 @@
+package main
+
+import "time"
+
+// The source of the wall-clock times recorded in treegen's outputs, so tests can pin them with --now.
+// Durations are still measured with the real clock.
+type clock interface {
+	Now() time.Time
+}
+
+// The real wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// A clock stopped at a fixed time
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+// Gets the clock from --now, or the real one if it isn't set
+func newClock(now string) (clock, error) {
+	if now == "" {
+		return systemClock{}, nil
+	}
+	t, err := parseTime(now)
+	if err != nil {
+		return nil, err
+	}
+	return fixedClock{now: t}, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Parses the two rulesets given to --compare-rulesets as "A,B"
+func parseRulesetPair(value string) ([2]uint64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return [2]uint64{}, fmt.Errorf("%s is not a pair of rulesets like 5,6", value)
+	}
+	var rulesets [2]uint64
+	for i, part := range parts {
+		ruleset, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil || ruleset == 0 {
+			return [2]uint64{}, fmt.Errorf("%s is not a valid ruleset", part)
+		}
+		rulesets[i] = ruleset
+	}
+	if rulesets[0] == rulesets[1] {
+		return [2]uint64{}, fmt.Errorf("both rulesets are %d", rulesets[0])
+	}
+	return rulesets, nil
+}
+
+// Generates the targeted interval under two rulesets from a single state fetch and prints how the results differ
+func (g *treeGenerator) compareRulesets(rulesets [2]uint64) error {
+	args, err := g.getTreegenArgs()
+	if err != nil {
+		return fmt.Errorf("error compiling treegen arguments: %w", err)
+	}
+	treegen, err := g.getGenerator(args)
+	if err != nil {
+		return err
+	}
+
+	var files [2]rprewards.IRewardsFile
+	for i, ruleset := range rulesets {
+		g.log.Printlnf("Generating interval %d with ruleset v%d...", args.index, ruleset)
+		files[i], err = treegen.GenerateTreeWithRuleset(ruleset)
+		if err != nil {
+			return fmt.Errorf("error generating Merkle tree with ruleset v%d: %w", ruleset, err)
+		}
+	}
+	g.printRulesetComparison(files)
+	return nil
+}
+
+// Generates the current interval's partial tree under the ruleset it would use by default and under a proposed one,
+// and prints which nodes would gain or lose if the proposed ruleset were activated
+func (g *treeGenerator) simulateRulesetUpgrade(proposed uint64) error {
+	args, err := g.getTreegenArgs()
+	if err != nil {
+		return fmt.Errorf("error compiling treegen arguments: %w", err)
+	}
+	treegen, err := g.getGenerator(args)
+	if err != nil {
+		return err
+	}
+
+	var files [2]rprewards.IRewardsFile
+	g.log.Printlnf("Generating interval %d with its default ruleset...", args.index)
+	files[0], err = treegen.GenerateTree()
+	if err != nil {
+		return fmt.Errorf("error generating Merkle tree with the default ruleset: %w", err)
+	}
+	current := files[0].GetHeader().RulesetVersion
+	if current == proposed {
+		return fmt.Errorf("interval %d already uses ruleset v%d by default", args.index, proposed)
+	}
+	g.log.Printlnf("Generating interval %d with the proposed ruleset v%d...", args.index, proposed)
+	files[1], err = treegen.GenerateTreeWithRuleset(proposed)
+	if err != nil {
+		return fmt.Errorf("error generating Merkle tree with ruleset v%d: %w", proposed, err)
+	}
+	g.printRulesetComparison(files)
+	return nil
+}
+
+// Prints how two rewards files for the same interval differ in their roots, totals, and each node's rewards
+func (g *treeGenerator) printRulesetComparison(files [2]rprewards.IRewardsFile) {
+	a, b := files[0].GetHeader(), files[1].GetHeader()
+
+	g.log.Println()
+	g.log.Printlnf("=== Ruleset v%d vs v%d ===", a.RulesetVersion, b.RulesetVersion)
+	g.log.Printlnf("Merkle root:        %s vs %s", a.MerkleRoot, b.MerkleRoot)
+	if a.TotalRewards != nil && b.TotalRewards != nil {
+		g.log.Printlnf("Collateral RPL:     %s", amountDelta(totalAmount(a.TotalRewards.TotalCollateralRpl), totalAmount(b.TotalRewards.TotalCollateralRpl)))
+		g.log.Printlnf("Oracle DAO RPL:     %s", amountDelta(totalAmount(a.TotalRewards.TotalOracleDaoRpl), totalAmount(b.TotalRewards.TotalOracleDaoRpl)))
+		g.log.Printlnf("Smoothing Pool ETH: %s", amountDelta(totalAmount(a.TotalRewards.TotalSmoothingPoolEth), totalAmount(b.TotalRewards.TotalSmoothingPoolEth)))
+		g.log.Printlnf("rETH share:         %s", amountDelta(totalAmount(a.TotalRewards.PoolStakerSmoothingPoolEth), totalAmount(b.TotalRewards.PoolStakerSmoothingPoolEth)))
+		g.log.Printlnf("Node operator ETH:  %s", amountDelta(totalAmount(a.TotalRewards.NodeOperatorSmoothingPoolEth), totalAmount(b.TotalRewards.NodeOperatorSmoothingPoolEth)))
+	}
+
+	// Every node that's in either file, so nodes that only one ruleset rewards show up too
+	addresses := map[common.Address]bool{}
+	for _, file := range files {
+		for _, address := range file.GetNodeAddresses() {
+			addresses[address] = true
+		}
+	}
+	sorted := make([]common.Address, 0, len(addresses))
+	for address := range addresses {
+		sorted = append(sorted, address)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Bytes(), sorted[j].Bytes()) < 0
+	})
+
+	// RPL and ETH aren't comparable, so nodes are counted as gaining or losing in each separately
+	changed := 0
+	var rplGainers, rplLosers, ethGainers, ethLosers int
+	for _, address := range sorted {
+		rplA, ethA := nodeRewards(files[0], address)
+		rplB, ethB := nodeRewards(files[1], address)
+		rplChange, ethChange := rplB.Cmp(rplA), ethB.Cmp(ethA)
+		if rplChange == 0 && ethChange == 0 {
+			continue
+		}
+		changed++
+		rplGainers, rplLosers = countChange(rplChange, rplGainers, rplLosers)
+		ethGainers, ethLosers = countChange(ethChange, ethGainers, ethLosers)
+		g.log.Printlnf("%s  RPL %s  ETH %s", address.Hex(), amountDelta(rplA, rplB), amountDelta(ethA, ethB))
+	}
+	g.log.Printlnf("%d of %d node(s) have different rewards.", changed, len(sorted))
+	g.log.Printlnf("RPL: %d node(s) gain and %d lose; ETH: %d node(s) gain and %d lose.", rplGainers, rplLosers, ethGainers, ethLosers)
+}
+
+// Adds a comparison result to the gain and loss counts
+func countChange(change int, gainers int, losers int) (int, int) {
+	if change > 0 {
+		gainers++
+	} else if change < 0 {
+		losers++
+	}
+	return gainers, losers
+}
+
+// Gets a node's total RPL and ETH in a rewards file, which are zero if the node isn't in it
+func nodeRewards(rewardsFile rprewards.IRewardsFile, address common.Address) (*big.Int, *big.Int) {
+	info, exists := rewardsFile.GetNodeRewardsInfo(address)
+	if !exists {
+		return big.NewInt(0), big.NewInt(0)
+	}
+	rpl := big.NewInt(0).Add(&info.GetCollateralRpl().Int, &info.GetOracleDaoRpl().Int)
+	return rpl, &info.GetSmoothingPoolEth().Int
+}
+
+// Formats two amounts and the change between them in ETH units
+func amountDelta(a, b *big.Int) string {
+	delta := big.NewInt(0).Sub(b, a)
+	return fmt.Sprintf("%.6f -> %.6f (%+.6f)", eth.WeiToEth(a), eth.WeiToEth(b), eth.WeiToEth(delta))
+}
+
+// Gets the amount of a header total, which is zero if the total is missing
+func totalAmount(total *rprewards.QuotedBigInt) *big.Int {
+	if total == nil {
+		return big.NewInt(0)
+	}
+	return &total.Int
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/services/state"
+)
+
+// Writes the minipool performance data as CSV, one row per minipool, next to the performance file in each output directory
+func (g *treeGenerator) writePerformanceCsv(rewardsFile rprewards.IRewardsFile, networkState *state.NetworkState) error {
+	perfFile := rewardsFile.GetMinipoolPerformanceFile()
+
+	// The performance file doesn't record node addresses, so get them from the state
+	nodes := make(map[common.Address]common.Address, len(networkState.MinipoolDetails))
+	for _, mpd := range networkState.MinipoolDetails {
+		nodes[mpd.MinipoolAddress] = mpd.NodeAddress
+	}
+
+	addresses := perfFile.GetMinipoolAddresses()
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	err := writer.Write([]string{"minipool", "node", "pubkey", "expectedAttestations", "successfulAttestations", "missedAttestations", "ethEarned"})
+	if err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+	for _, address := range addresses {
+		perf, _ := perfFile.GetSmoothingPoolPerformance(address)
+		pubkey, err := perf.GetPubkey()
+		if err != nil {
+			return fmt.Errorf("error getting pubkey for minipool %s: %w", address.Hex(), err)
+		}
+		node := nodes[address]
+		if g.anonymize {
+			node = anonymizeAddress(g.anonymizeKey, node)
+		}
+		successful := perf.GetSuccessfulAttestationCount()
+		missed := perf.GetMissedAttestationCount()
+		err = writer.Write([]string{
+			address.Hex(),
+			node.Hex(),
+			pubkey.Hex(),
+			fmt.Sprint(successful + missed),
+			fmt.Sprint(successful),
+			fmt.Sprint(missed),
+			perf.GetEthEarned().String(),
+		})
+		if err != nil {
+			return fmt.Errorf("error writing CSV row for minipool %s: %w", address.Hex(), err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+
+	index := rewardsFile.GetHeader().Index
+	for _, outputDir := range g.outputDirs {
+		_, minipoolPerformancePath := g.outputPaths(outputDir, index)
+		path := strings.TrimSuffix(minipoolPerformancePath, ".json") + ".csv"
+		err = writeFileAtomic(path, buffer.Bytes(), 0644)
+		if err != nil {
+			return fmt.Errorf("error saving minipool performance CSV to %s: %w", path, err)
+		}
+		g.log.Printlnf("Saved minipool performance CSV to %s", path)
+		g.audit.addOutput(path)
+	}
+
+	return nil
+}
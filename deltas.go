@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Loads the rewards file for the interval before the generated one, from --previous-rewards-file or else from where
+// treegen would have saved it in the first output directory
+func (g *treeGenerator) loadPreviousRewardsFile(index uint64) (rprewards.IRewardsFile, error) {
+	if index == 0 {
+		return nil, fmt.Errorf("interval 0 has no previous interval to compare against")
+	}
+	path := g.previousRewardsFile
+	if path == "" {
+		path, _ = g.outputPaths(g.outputDirs[0], index-1)
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the interval %d rewards file; generate it first or pass --previous-rewards-file: %w", index-1, err)
+	}
+	previous, err := rprewards.DeserializeRewardsFile(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error deserializing %s: %w", path, err)
+	}
+	if previous.GetHeader().Index != index-1 {
+		return nil, fmt.Errorf("%s is for interval %d, not the previous interval %d", path, previous.GetHeader().Index, index-1)
+	}
+	return previous, nil
+}
+
+// Writes each node's change in RPL and ETH since the previous interval as CSV, marking nodes that were added or dropped.
+// Amounts are in wei. Nodes whose rewards didn't change are left out.
+func (g *treeGenerator) writePreviousDeltas(rewardsFile rprewards.IRewardsFile, path string) error {
+	index := rewardsFile.GetHeader().Index
+	previous, err := g.loadPreviousRewardsFile(index)
+	if err != nil {
+		return err
+	}
+
+	// Every node in either interval
+	inPrevious := map[common.Address]bool{}
+	for _, address := range previous.GetNodeAddresses() {
+		inPrevious[address] = true
+	}
+	inCurrent := map[common.Address]bool{}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		inCurrent[address] = true
+	}
+	addresses := make([]common.Address, 0, len(inCurrent))
+	for address := range inCurrent {
+		addresses = append(addresses, address)
+	}
+	for address := range inPrevious {
+		if !inCurrent[address] {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	err = writer.Write([]string{"node", "status", "previousRpl", "rpl", "rplDelta", "previousEth", "eth", "ethDelta"})
+	if err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+	var added, dropped, changed int
+	for _, address := range addresses {
+		rplBefore, ethBefore := nodeRewards(previous, address)
+		rplAfter, ethAfter := nodeRewards(rewardsFile, address)
+		status := "changed"
+		switch {
+		case !inPrevious[address]:
+			status = "added"
+			added++
+		case !inCurrent[address]:
+			status = "dropped"
+			dropped++
+		case rplBefore.Cmp(rplAfter) == 0 && ethBefore.Cmp(ethAfter) == 0:
+			continue
+		default:
+			changed++
+		}
+
+		node := address
+		if g.anonymize {
+			node = anonymizeAddress(g.anonymizeKey, node)
+		}
+		err = writer.Write([]string{
+			node.Hex(),
+			status,
+			rplBefore.String(),
+			rplAfter.String(),
+			big.NewInt(0).Sub(rplAfter, rplBefore).String(),
+			ethBefore.String(),
+			ethAfter.String(),
+			big.NewInt(0).Sub(ethAfter, ethBefore).String(),
+		})
+		if err != nil {
+			return fmt.Errorf("error writing CSV row for node %s: %w", address.Hex(), err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+
+	err = writeFileAtomic(path, buffer.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("error saving deltas to %s: %w", path, err)
+	}
+	g.log.Printlnf("Compared with interval %d: %d node(s) added, %d dropped, and %d with changed rewards. Saved the deltas to %s", index-1, added, dropped, changed, path)
+	g.audit.addOutput(path)
+	return nil
+}
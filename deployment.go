@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/goccy/go-json"
+)
+
+// Loads the RocketStorage address from a deployment file mapping contract names to addresses.
+// rocketpool-go resolves every other contract through RocketStorage and has no way to register
+// individual overrides, so rocketStorage is the only name that can be honored.
+func loadDeploymentStorageAddress(path string) (common.Address, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	var deployment map[string]string
+	if err := json.Unmarshal(bytes, &deployment); err != nil {
+		return common.Address{}, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	for name := range deployment {
+		if name != "rocketStorage" {
+			return common.Address{}, fmt.Errorf("%s overrides contract %s, but only rocketStorage can be overridden; every other contract is looked up from it", path, name)
+		}
+	}
+	address, exists := deployment["rocketStorage"]
+	if !exists {
+		return common.Address{}, fmt.Errorf("%s does not contain a rocketStorage address", path)
+	}
+	if !common.IsHexAddress(address) {
+		return common.Address{}, fmt.Errorf("%s is not a valid rocketStorage address", address)
+	}
+
+	return common.HexToAddress(address), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// An EC client that bounds the number of requests in flight at once.
+// Every read the Rocket Pool wrapper and state manager make goes through it, so treegen's own calls and the
+// state manager's concurrent ones share the same limit. Transaction methods are passed through since treegen never sends any.
+type limitedClient struct {
+	*ethclient.Client
+	sem chan struct{}
+}
+
+// Creates a client that allows at most maxRequests concurrent EC requests
+func newLimitedClient(ec *ethclient.Client, maxRequests int) *limitedClient {
+	return &limitedClient{
+		Client: ec,
+		sem:    make(chan struct{}, maxRequests),
+	}
+}
+
+// Waits for a free request slot; the returned function releases it
+func (c *limitedClient) acquire() func() {
+	c.sem <- struct{}{}
+	return func() { <-c.sem }
+}
+
+func (c *limitedClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	defer c.acquire()()
+	return c.Client.CodeAt(ctx, contract, blockNumber)
+}
+
+func (c *limitedClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	defer c.acquire()()
+	return c.Client.CallContract(ctx, call, blockNumber)
+}
+
+func (c *limitedClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	defer c.acquire()()
+	return c.Client.HeaderByHash(ctx, hash)
+}
+
+func (c *limitedClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	defer c.acquire()()
+	return c.Client.HeaderByNumber(ctx, number)
+}
+
+func (c *limitedClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	defer c.acquire()()
+	return c.Client.FilterLogs(ctx, query)
+}
+
+func (c *limitedClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	defer c.acquire()()
+	return c.Client.TransactionReceipt(ctx, txHash)
+}
+
+func (c *limitedClient) BlockNumber(ctx context.Context) (uint64, error) {
+	defer c.acquire()()
+	return c.Client.BlockNumber(ctx)
+}
+
+func (c *limitedClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	defer c.acquire()()
+	return c.Client.BalanceAt(ctx, account, blockNumber)
+}
+
+func (c *limitedClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	defer c.acquire()()
+	return c.Client.TransactionByHash(ctx, hash)
+}
+
+func (c *limitedClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	defer c.acquire()()
+	return c.Client.NonceAt(ctx, account, blockNumber)
+}
+
+func (c *limitedClient) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	defer c.acquire()()
+	return c.Client.SyncProgress(ctx)
+}
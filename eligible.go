@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rocket-pool/rocketpool-go/types"
+)
+
+// Prints how many nodes and minipools can expect rewards at the snapshot, from the network state alone, without generating a tree.
+// The Smartnode's generator doesn't expose its eligibility checks, so this approximates them: a node counts for RPL if it has
+// an effective RPL stake and for Smoothing Pool ETH if it's opted in, as long as it has a staking minipool. Attestation
+// performance and opt-in changes during the interval aren't considered, so the tree can have fewer Smoothing Pool nodes.
+func (g *treeGenerator) printEligibleNodes() error {
+	args, err := g.getTreegenArgs()
+	if err != nil {
+		return fmt.Errorf("error compiling treegen arguments: %w", err)
+	}
+	networkState := args.state
+
+	var nodes, rplNodes, smoothingPoolNodes, minipools int
+	for _, node := range networkState.NodeDetails {
+		staking := 0
+		for _, mpd := range networkState.MinipoolDetailsByNode[node.NodeAddress] {
+			if mpd.Status == types.Staking && !mpd.Finalised {
+				staking++
+			}
+		}
+		if staking == 0 {
+			continue
+		}
+
+		rpl := node.EffectiveRPLStake != nil && node.EffectiveRPLStake.Sign() > 0
+		if rpl {
+			rplNodes++
+		}
+		if node.SmoothingPoolRegistrationState {
+			smoothingPoolNodes++
+		}
+		if rpl || node.SmoothingPoolRegistrationState {
+			nodes++
+			minipools += staking
+		}
+	}
+
+	g.log.Printlnf("Eligible at slot %d (approximate):", networkState.BeaconSlotNumber)
+	g.log.Printlnf("    Nodes:                    %d of %d", nodes, len(networkState.NodeDetails))
+	g.log.Printlnf("    Nodes earning RPL:        %d", rplNodes)
+	g.log.Printlnf("    Smoothing Pool nodes:     %d", smoothingPoolNodes)
+	g.log.Printlnf("    Their staking minipools:  %d", minipools)
+	return nil
+}
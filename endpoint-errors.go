@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An error from a call to the EC or BN, which may go away if the call is made again
+type endpointError struct {
+	err error
+}
+
+func (e *endpointError) Error() string {
+	return e.err.Error()
+}
+
+func (e *endpointError) Unwrap() error {
+	return e.err
+}
+
+// Checks whether an error came from a call to the EC or BN rather than from the generation itself
+func isEndpointError(err error) bool {
+	var endpointErr *endpointError
+	return errors.As(err, &endpointErr)
+}
+
+// Annotates an error from an EC call with what was being done, the EL block it was made against, and the EC it was made to.
+// A block of 0 means the call was made against the EC's head.
+func (g *treeGenerator) ecError(err error, block uint64, operation string) error {
+	at := "the EC's head"
+	if block != 0 {
+		at = fmt.Sprintf("EL block %d", block)
+	}
+	return &endpointError{err: fmt.Errorf("error %s at %s [EC %s]: %w", operation, at, redactUrl(g.ecUrl), err)}
+}
+
+// Annotates an error from a BN call with what was being done, the slot it was made for, and the BN it was made to
+func (g *treeGenerator) bnError(err error, slot uint64, operation string) error {
+	return &endpointError{err: fmt.Errorf("error %s at slot %d [BN %s]: %w", operation, slot, redactUrl(g.bnUrl), err)}
+}
+
+// Annotates an error from the network state fetch, which reads from both endpoints
+func (g *treeGenerator) stateError(err error, slot uint64) error {
+	return &endpointError{err: fmt.Errorf("error getting the network state at slot %d [BN %s, EC %s]: %w", slot, redactUrl(g.bnUrl), redactUrl(g.ecUrl), err)}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// Reports how far back the EC and BN retain historical state by binary searching for the oldest block / slot
+// each can serve state for. This assumes state is available for every block from that point on, which holds for
+// archive and pruned nodes but not for a BN that is still reconstructing states after a checkpoint sync.
+func EndpointHealth(c *cli.Context) error {
+	// Configure
+	configureHTTP()
+	logger := log.NewColorLogger(color.FgHiWhite)
+
+	conn, err := connect(c, &logger, nil)
+	if err != nil {
+		return fmt.Errorf("connection check failed: %w", err)
+	}
+
+	// The EC's oldest block with state
+	head, err := conn.rp.Client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("error getting the EC's latest block: %w", err)
+	}
+	storage := common.HexToAddress(conn.cfg.Smartnode.GetStorageAddress())
+	headBlock := head.Number.Uint64()
+	logger.Printlnf("Searching for the oldest EL block the EC has state for (head is %d)...", headBlock)
+	oldestBlock := uint64(sort.Search(int(headBlock+1), func(i int) bool {
+		_, err := conn.rp.Client.BalanceAt(context.Background(), storage, big.NewInt(int64(i)))
+		return err == nil
+	}))
+	if oldestBlock > headBlock {
+		return fmt.Errorf("the EC can't serve state for any block, including its head")
+	}
+	oldestHeader, err := conn.rp.Client.HeaderByNumber(context.Background(), big.NewInt(0).SetUint64(oldestBlock))
+	if err != nil {
+		return fmt.Errorf("error getting EL block %d: %w", oldestBlock, err)
+	}
+	oldestTime := time.Unix(int64(oldestHeader.Time), 0)
+	logger.Printlnf("EC: state is available from EL block %d (%s), %d blocks or about %s back", oldestBlock, oldestTime, headBlock-oldestBlock, time.Since(oldestTime).Round(time.Hour))
+
+	// The BN's oldest slot with state
+	beaconHead, err := conn.bn.GetBeaconHead()
+	if err != nil {
+		return fmt.Errorf("error getting the BN's head: %w", err)
+	}
+	headSlot := beaconHead.Epoch * conn.beaconConfig.SlotsPerEpoch
+	logger.Printlnf("Searching for the oldest slot the BN has state for (head is about %d)...", headSlot)
+	oldestSlot := uint64(sort.Search(int(headSlot+1), func(i int) bool {
+		slot := uint64(i)
+		_, err := conn.bn.GetValidatorStatusByIndex("0", &beacon.ValidatorStatusOptions{
+			Slot: &slot,
+		})
+		return err == nil
+	}))
+	if oldestSlot > headSlot {
+		return fmt.Errorf("the BN can't serve state for any slot, including its head")
+	}
+	oldestSlotTime := time.Unix(int64(conn.beaconConfig.GenesisTime+oldestSlot*conn.beaconConfig.SecondsPerSlot), 0)
+	logger.Printlnf("BN: state is available from slot %d (%s), %d slots or about %s back", oldestSlot, oldestSlotTime, headSlot-oldestSlot, time.Since(oldestSlotTime).Round(time.Hour))
+
+	return nil
+}
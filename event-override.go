@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/rocket-pool/rocketpool-go/rewards"
+	"github.com/urfave/cli/v2"
+)
+
+// The flags that must all be provided to describe an interval without its submission event
+var manualEventFlags = []string{"consensus-block", "execution-block", "interval-start", "interval-end"}
+
+// Builds the rewards event for an interval from the command line instead of looking it up in the EC's logs,
+// for deployments where the submission event isn't retrievable.
+// The canonical Merkle root isn't known this way, so the generated root can't be checked against it.
+func (g *treeGenerator) manualRewardsEvent(c *cli.Context, index uint64) (*rewards.RewardsEvent, error) {
+	var missing []string
+	for _, name := range manualEventFlags {
+		if !c.IsSet(name) {
+			missing = append(missing, "--"+name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("skipping the rewards event lookup requires --%s, but %s were not provided", strings.Join(manualEventFlags, ", --"), strings.Join(missing, ", "))
+	}
+
+	startTime, err := parseTime(c.String("interval-start"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing interval-start: %w", err)
+	}
+	endTime, err := parseTime(c.String("interval-end"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing interval-end: %w", err)
+	}
+	if !endTime.After(startTime) {
+		return nil, fmt.Errorf("interval-end %s must be after interval-start %s", endTime, startTime)
+	}
+	executionBlock := big.NewInt(0).SetUint64(c.Uint64("execution-block"))
+
+	// The event reports how many intervals the submission covered; derive it the same way from the interval time
+	intervalTime, err := g.getIntervalTime(&bind.CallOpts{BlockNumber: executionBlock})
+	if err != nil {
+		return nil, err
+	}
+	intervalsPassed := uint64(endTime.Sub(startTime) / intervalTime)
+	if intervalsPassed == 0 {
+		return nil, fmt.Errorf("the window from interval-start %s to interval-end %s is shorter than the interval time of %s, so no intervals passed and the tree would have no inflation; set --interval-time to the interval length this window represents", startTime, endTime, intervalTime)
+	}
+	g.logTiming("manual event: startTime=%s (%d) endTime=%s (%d) intervalTime=%s (%d s) window=%s intervalsPassed=%d", startTime.UTC(), startTime.Unix(), endTime.UTC(), endTime.Unix(), intervalTime, int64(intervalTime.Seconds()), endTime.Sub(startTime), intervalsPassed)
+
+	g.log.Printlnf("Using interval %d from the command line: consensus block %d, EL block %d, %s to %s.", index, c.Uint64("consensus-block"), executionBlock.Uint64(), startTime, endTime)
+	return &rewards.RewardsEvent{
+		Index:             big.NewInt(0).SetUint64(index),
+		ExecutionBlock:    executionBlock,
+		ConsensusBlock:    big.NewInt(0).SetUint64(c.Uint64("consensus-block")),
+		IntervalsPassed:   big.NewInt(0).SetUint64(intervalsPassed),
+		IntervalStartTime: startTime,
+		IntervalEndTime:   endTime,
+	}, nil
+}
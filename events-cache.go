@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/rocket-pool/rocketpool-go/rewards"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Submitted rewards events by interval, persisted by --events-cache so repeated runs don't rescan the EC's logs.
+// Events can't change once submitted, so cached entries never expire; --refresh-events-cache starts over.
+type eventsCache struct {
+	path   string
+	events map[uint64]rewards.RewardsEvent
+}
+
+// Loads the events cache at the given path, or starts an empty one if it doesn't exist yet or refresh is set
+func loadEventsCache(path string, refresh bool) (*eventsCache, error) {
+	cache := &eventsCache{
+		path:   path,
+		events: map[uint64]rewards.RewardsEvent{},
+	}
+	if refresh {
+		return cache, nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading events cache %s: %w", path, err)
+	}
+	if err := json.Unmarshal(bytes, &cache.events); err != nil {
+		return nil, fmt.Errorf("error parsing events cache %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// Gets the rewards event for an interval, from the events cache if one is in use and has it.
+// Newly looked up events are added to the cache and saved right away.
+func (g *treeGenerator) getRewardsEvent(index uint64) (rewards.RewardsEvent, error) {
+	if g.eventsCache != nil {
+		if event, exists := g.eventsCache.events[index]; exists {
+			return event, nil
+		}
+	}
+
+	event, err := rprewards.GetRewardSnapshotEvent(g.rp, g.cfg, index, nil)
+	if err != nil {
+		return event, err
+	}
+	if g.eventsCache == nil {
+		return event, nil
+	}
+
+	g.eventsCache.events[index] = event
+	bytes, err := json.Marshal(g.eventsCache.events)
+	if err != nil {
+		return event, fmt.Errorf("error serializing events cache: %w", err)
+	}
+	if err := writeFileAtomic(g.eventsCache.path, bytes, 0644); err != nil {
+		return event, fmt.Errorf("error saving events cache to %s: %w", g.eventsCache.path, err)
+	}
+	g.log.Printlnf("Cached the rewards event for interval %d in %s", index, g.eventsCache.path)
+	return event, nil
+}
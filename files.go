@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rocket-pool/smartnode/shared/services/config"
+)
+
+// Writes data to a temporary file in the same directory as path, then renames it into place
+// so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file if anything goes wrong before the rename
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("error writing temporary file %s: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("error syncing temporary file %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file %s: %w", tmpPath, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("error setting permissions on temporary file %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error moving temporary file into place: %w", err)
+	}
+
+	success = true
+	return nil
+}
+
+// Checks that a directory exists and that files can be created in it.
+// An empty path refers to the current working directory.
+func checkDirWritable(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, ".treegen-write-check-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// Gets the directory the Smartnode's claim flow reads rewards files from under its data directory, creating it if needed.
+// The data directory itself must already exist, so a typo isn't silently turned into a new tree.
+func smartnodeRewardsDir(dataDir string) (string, error) {
+	info, err := os.Stat(dataDir)
+	if err != nil {
+		return "", fmt.Errorf("error reading the Smartnode data directory %s: %w", dataDir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("the Smartnode data directory %s is not a directory", dataDir)
+	}
+
+	dir := filepath.Join(dataDir, config.RewardsTreesFolder)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// Gets the subdirectory of an output directory for the given network, creating it if needed.
+// The output directory itself must already exist, like the Smartnode data directory.
+func networkOutputDir(outputDir string, network string) (string, error) {
+	if outputDir == "" {
+		outputDir = "."
+	}
+	info, err := os.Stat(outputDir)
+	if err != nil {
+		return "", fmt.Errorf("error reading output directory %s: %w", outputDir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("output directory %s is not a directory", outputDir)
+	}
+
+	dir := filepath.Join(outputDir, network)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// Formats a byte count for display
+func formatSize(size int) string {
+	return fmt.Sprintf("%d bytes (%.2f MiB)", size, float64(size)/(1024*1024))
+}
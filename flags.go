@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Flags that only affect the files written by a full tree generation
+var fileOutputFlags = []string{"schema-version", "node-filter", "omit-zero-rewards", "anonymize", "no-proofs", "split-proofs", "performance-csv", "rpl-stakes", "dump-merkle-tree", "dump-leaves-csv", "previous-deltas", "report", "reward-split-file", "snapshot-info", "estimate-sizes", "print-hashes", "pin"}
+
+// Rejects flag combinations where one flag would otherwise be silently ignored
+func validateFlags(c *cli.Context) error {
+	// Bool flags only count when enabled, so e.g. --pin=false doesn't conflict with anything
+	used := func(names []string) []string {
+		var found []string
+		for _, name := range names {
+			if c.IsSet(name) && (c.Value(name) != false) {
+				found = append(found, "--"+name)
+			}
+		}
+		return found
+	}
+	conflict := func(mode string, names []string) error {
+		if found := used(names); len(found) > 0 {
+			return fmt.Errorf("--%s does not write a rewards tree, so it cannot be combined with %s", mode, strings.Join(found, ", "))
+		}
+		return nil
+	}
+
+	if c.Bool("approximate-only") && c.Bool("network-info") {
+		return fmt.Errorf("--approximate-only and --network-info are separate modes and cannot be combined")
+	}
+	if c.IsSet("roster") && (c.Bool("approximate-only") || c.Bool("network-info")) {
+		return fmt.Errorf("--roster is a separate mode and cannot be combined with --approximate-only or --network-info")
+	}
+	if c.IsSet("compare-rulesets") {
+		if found := used([]string{"approximate-only", "network-info", "roster", "ruleset"}); len(found) > 0 {
+			return fmt.Errorf("--compare-rulesets is a separate mode and cannot be combined with %s", strings.Join(found, ", "))
+		}
+	}
+	if c.IsSet("simulate-ruleset-upgrade") {
+		if c.Uint64("simulate-ruleset-upgrade") == 0 {
+			return fmt.Errorf("--simulate-ruleset-upgrade must be a ruleset version")
+		}
+		if found := used([]string{"approximate-only", "network-info", "roster", "compare-rulesets", "ruleset"}); len(found) > 0 {
+			return fmt.Errorf("--simulate-ruleset-upgrade is a separate mode and cannot be combined with %s", strings.Join(found, ", "))
+		}
+	}
+	if c.Bool("print-eligible-nodes") {
+		if found := used([]string{"approximate-only", "network-info", "roster", "compare-rulesets", "simulate-ruleset-upgrade"}); len(found) > 0 {
+			return fmt.Errorf("--print-eligible-nodes is a separate mode and cannot be combined with %s", strings.Join(found, ", "))
+		}
+	}
+	for _, mode := range []string{"approximate-only", "network-info", "roster", "compare-rulesets", "simulate-ruleset-upgrade", "print-eligible-nodes"} {
+		if !c.IsSet(mode) || c.Value(mode) == false {
+			continue
+		}
+		others := append([]string{"root-only", "only-minipool-performance", "expected-root", "print-tree-stats", "check-totals", "verify-proofs-sample", "compare-ipfs", "assert-smartnode-compat", "claim-calldata", "attestation-detail", "node-summary", "reward-split", "watch"}, fileOutputFlags...)
+		if err := conflict(mode, others); err != nil {
+			return err
+		}
+	}
+	if c.IsSet("report-top") && c.Int("report-top") < 1 {
+		return fmt.Errorf("--report-top must be at least 1")
+	}
+	if c.IsSet("previous-rewards-file") && !c.IsSet("previous-deltas") {
+		return fmt.Errorf("--previous-rewards-file requires --previous-deltas")
+	}
+	if c.Bool("nearest-el-block") && c.Bool("beacon-fallback-to-el-time") {
+		return fmt.Errorf("--nearest-el-block and --beacon-fallback-to-el-time are different ways to resolve a snapshot block without an execution payload, so only one can be used")
+	}
+	if c.IsSet("anonymize-salt") && !c.Bool("anonymize") {
+		return fmt.Errorf("--anonymize-salt requires --anonymize")
+	}
+	if c.IsSet("split-proofs") && c.Bool("anonymize") {
+		return fmt.Errorf("--split-proofs writes a file per real node address to prove against the tree, so it cannot be combined with --anonymize")
+	}
+	if c.IsSet("split-proofs") && c.Bool("no-proofs") {
+		return fmt.Errorf("--split-proofs writes each node's Merkle proof out, so it cannot be combined with --no-proofs")
+	}
+	if c.IsSet("dump-leaves-csv") && c.Bool("anonymize") {
+		return fmt.Errorf("--dump-leaves-csv needs the real node addresses to rebuild the tree, so it cannot be combined with --anonymize")
+	}
+	if c.Bool("refresh-events-cache") && !c.IsSet("events-cache") {
+		return fmt.Errorf("--refresh-events-cache requires --events-cache")
+	}
+	if c.Bool("watch") && c.IsSet("el-block-hash") {
+		return fmt.Errorf("--watch generates a new snapshot for every interval, so it cannot be combined with --el-block-hash")
+	}
+	if c.Bool("root-only") {
+		if err := conflict("root-only", append([]string{"only-minipool-performance"}, fileOutputFlags...)); err != nil {
+			return err
+		}
+	}
+	if c.Bool("only-minipool-performance") {
+		if found := used([]string{"node-filter", "omit-zero-rewards", "anonymize", "no-proofs", "split-proofs"}); len(found) > 0 {
+			return fmt.Errorf("--only-minipool-performance skips the rewards tree, so it cannot be combined with %s", strings.Join(found, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/rocket-pool/rocketpool-go/rewards"
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// Checks that the EC and BN are reachable and capable of generating past trees, without generating one
+func Healthcheck(c *cli.Context) error {
+	// Configure
+	configureHTTP()
+	logger := log.NewColorLogger(color.FgHiWhite)
+	errLogger := log.NewColorLogger(color.FgRed)
+
+	// Connecting also verifies that the network can be detected from the BN
+	conn, err := connect(c, &logger, nil)
+	if err != nil {
+		return fmt.Errorf("connection check failed: %w", err)
+	}
+	logger.Printlnf("OK: connected to the EC at %s and the BN at %s (chain ID %d)", conn.ecUrl, conn.bnUrl, conn.chainID)
+
+	failures := 0
+	fail := func(format string, v ...interface{}) {
+		failures++
+		errLogger.Printlnf("FAIL: "+format, v...)
+	}
+
+	// Get the current reward index and the time of the next interval
+	index, err := rewards.GetRewardIndex(conn.rp, nil)
+	if err != nil {
+		fail("error getting current reward index: %s", err.Error())
+	} else {
+		logger.Printlnf("OK: current reward index is %d", index.Uint64())
+	}
+	startTime, err := rewards.GetClaimIntervalTimeStart(conn.rp, nil)
+	if err != nil {
+		fail("error getting claim interval start time: %s", err.Error())
+	}
+	intervalTime, err := rewards.GetClaimIntervalTime(conn.rp, nil)
+	if err != nil {
+		fail("error getting claim interval time: %s", err.Error())
+	}
+	if !startTime.IsZero() && intervalTime > 0 {
+		nextInterval := startTime.Add(intervalTime)
+		logger.Printlnf("OK: next interval ends at %s (in %s)", nextInterval, time.Until(nextInterval).Round(time.Second))
+	}
+
+	// Probe archive capability against the end of the previous interval, which is what past tree generation needs
+	if index != nil && index.Uint64() > 0 {
+		event, err := rprewards.GetRewardSnapshotEvent(conn.rp, conn.cfg, index.Uint64()-1, nil)
+		if err != nil {
+			fail("error getting rewards event for interval %d: %s", index.Uint64()-1, err.Error())
+		} else {
+			checkArchiveEc(conn, event, &logger, fail)
+			checkArchiveBn(conn, event, &logger, fail)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d health check(s) failed", failures)
+	}
+	logger.Printlnf("All health checks passed.")
+	return nil
+}
+
+// Checks that the EC can serve historical state at the end of the given interval
+func checkArchiveEc(conn *connections, event rewards.RewardsEvent, logger *log.ColorLogger, fail func(string, ...interface{})) {
+	block := event.ExecutionBlock
+	_, err := conn.rp.Client.BalanceAt(context.Background(), common.HexToAddress(conn.cfg.Smartnode.GetStorageAddress()), block)
+	if err != nil {
+		fail("EC could not serve state for EL block %d; it is likely not an archive node: %s", block.Uint64(), err.Error())
+		return
+	}
+	logger.Printlnf("OK: EC served historical state for EL block %d", block.Uint64())
+}
+
+// Checks that the BN can replay the state at the end of the given interval
+func checkArchiveBn(conn *connections, event rewards.RewardsEvent, logger *log.ColorLogger, fail func(string, ...interface{})) {
+	slot := event.ConsensusBlock.Uint64()
+	_, err := conn.bn.GetValidatorStatusByIndex("0", &beacon.ValidatorStatusOptions{
+		Slot: &slot,
+	})
+	if err != nil {
+		fail("BN could not serve state for slot %d; it is likely not an archive node: %s", slot, err.Error())
+		return
+	}
+	logger.Printlnf("OK: BN served historical state for slot %d", slot)
+}
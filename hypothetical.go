@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/rocket-pool/rocketpool-go/rewards"
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+	"github.com/urfave/cli/v2"
+)
+
+// Builds a rewards event for an arbitrary window given by --interval-start and --interval-end, for "what if the interval
+// were these dates" analysis. The snapshot is the last block of the last epoch that ends by --interval-end, the window
+// starts at the first block at or after --interval-start, and it counts as one interval passed.
+// The interval index is whichever interval the snapshot falls in, so the ruleset matches that interval's.
+// The result is not canonical and won't match any on-chain submission. Returns the event and the window's start slot.
+func (g *treeGenerator) hypotheticalRewardsEvent(c *cli.Context) (*rewards.RewardsEvent, uint64, error) {
+	if !c.IsSet("interval-start") || !c.IsSet("interval-end") {
+		return nil, 0, fmt.Errorf("hypothetical-window requires interval-start and interval-end")
+	}
+	startTime, err := parseTime(c.String("interval-start"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error parsing interval-start: %w", err)
+	}
+	endTime, err := parseTime(c.String("interval-end"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error parsing interval-end: %w", err)
+	}
+	if !endTime.After(startTime) {
+		return nil, 0, fmt.Errorf("interval-end %s must be after interval-start %s", endTime, startTime)
+	}
+	genesisTime := time.Unix(int64(g.beaconConfig.GenesisTime), 0)
+	if startTime.Before(genesisTime) {
+		return nil, 0, fmt.Errorf("interval-start %s is before the beacon chain genesis (%s)", startTime, genesisTime)
+	}
+	slotDuration := time.Duration(g.beaconConfig.SecondsPerSlot) * time.Second
+
+	// The snapshot has to be at the end of an epoch, like a real submission, and finalized
+	endEpoch := uint64(endTime.Sub(genesisTime)/slotDuration+1) / g.beaconConfig.SlotsPerEpoch
+	if endEpoch == 0 {
+		return nil, 0, fmt.Errorf("interval-end %s is before the end of the first epoch", endTime)
+	}
+	endEpoch--
+	head, err := g.bn.GetBeaconHead()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting the beacon head: %w", err)
+	}
+	if endEpoch > head.FinalizedEpoch {
+		return nil, 0, fmt.Errorf("interval-end %s is in epoch %d, which isn't finalized yet; the latest finalized epoch is %d", endTime, endEpoch, head.FinalizedEpoch)
+	}
+	endBlock, err := g.lastBlockInEpoch(endEpoch)
+	if err != nil {
+		return nil, 0, err
+	}
+	if endBlock == nil {
+		return nil, 0, fmt.Errorf("unable to find any valid blocks in epoch %d", endEpoch)
+	}
+	if endBlock.ExecutionBlockNumber == 0 {
+		return nil, 0, fmt.Errorf("slot %d has no execution payload; hypothetical windows must end after the Merge", endBlock.Slot)
+	}
+
+	startBlock, err := g.firstBlockAtOrAfter(uint64((startTime.Sub(genesisTime) + slotDuration - 1) / slotDuration))
+	if err != nil {
+		return nil, 0, err
+	}
+	if startBlock.Slot > endBlock.Slot {
+		return nil, 0, fmt.Errorf("the window from %s to %s doesn't contain a full epoch", startTime, endTime)
+	}
+
+	executionBlock := big.NewInt(0).SetUint64(endBlock.ExecutionBlockNumber)
+	index, err := rewards.GetRewardIndex(g.rp, &bind.CallOpts{BlockNumber: executionBlock})
+	if err != nil {
+		return nil, 0, g.ecError(err, executionBlock.Uint64(), "getting the reward index")
+	}
+
+	// The files are named after the interval, so don't replace that interval's real tree
+	for _, outputDir := range g.outputDirs {
+		path, _ := g.outputPaths(outputDir, index.Uint64())
+		if _, err := os.Stat(path); err == nil {
+			return nil, 0, fmt.Errorf("%s already exists and would be replaced by the hypothetical tree; use another output directory", path)
+		}
+	}
+
+	g.warn(warningHypotheticalWindow, "generating a hypothetical tree from %s (slot %d) to %s (slot %d, EL block %d) under interval %d's rules. It is not canonical and won't match any on-chain submission.", startTime, startBlock.Slot, endTime, endBlock.Slot, executionBlock.Uint64(), index.Uint64())
+	return &rewards.RewardsEvent{
+		Index:             index,
+		ExecutionBlock:    executionBlock,
+		ConsensusBlock:    big.NewInt(0).SetUint64(endBlock.Slot),
+		IntervalsPassed:   big.NewInt(1),
+		IntervalStartTime: startTime,
+		IntervalEndTime:   endTime,
+	}, startBlock.Slot, nil
+}
+
+// Gets the first proposed block at or up to an epoch after the given slot
+func (g *treeGenerator) firstBlockAtOrAfter(slot uint64) (*beacon.BeaconBlock, error) {
+	for i := uint64(0); i < g.beaconConfig.SlotsPerEpoch; i++ {
+		block, exists, err := g.bn.GetBeaconBlock(fmt.Sprint(slot + i))
+		if err != nil {
+			return nil, g.bnError(err, slot+i, "getting the beacon block")
+		}
+		if exists {
+			return &block, nil
+		}
+		g.logMissingSlot(slot + i)
+	}
+	return nil, fmt.Errorf("no proposed block in the epoch starting at slot %d", slot)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rocket-pool/smartnode/shared/services/config"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// The most differences to print; the rest are only counted
+const maxIpfsDifferences = 50
+
+// Downloads the canonical rewards file for the interval from an IPFS gateway, by the CID in its rewards event, and
+// compares every field with the generated file. This is stricter than the Merkle root check, which only covers the leaves.
+func (g *treeGenerator) compareWithIpfs(rewardsFile rprewards.IRewardsFile) error {
+	canonical, cid, err := g.downloadCanonicalRewardsFile(rewardsFile, "compare-ipfs")
+	if err != nil {
+		return err
+	}
+	generated, err := json.Marshal(rewardsFile)
+	if err != nil {
+		return fmt.Errorf("error serializing rewards file into JSON: %w", err)
+	}
+
+	var canonicalValue, generatedValue interface{}
+	if err := decodeJsonNumbers(canonical, &canonicalValue); err != nil {
+		return fmt.Errorf("error parsing the canonical rewards file: %w", err)
+	}
+	if err := decodeJsonNumbers(generated, &generatedValue); err != nil {
+		return fmt.Errorf("error parsing the generated rewards file: %w", err)
+	}
+
+	// The performance file's CID depends on how it was uploaded, which treegen doesn't reproduce
+	for _, value := range []interface{}{canonicalValue, generatedValue} {
+		if fields, ok := value.(map[string]interface{}); ok {
+			delete(fields, "minipoolPerformanceFileCid")
+		}
+	}
+
+	differences := []string{}
+	diffJsonValues("", canonicalValue, generatedValue, &differences)
+	if len(differences) > 0 {
+		for i, difference := range differences {
+			if i == maxIpfsDifferences {
+				g.errLog.Printlnf("... and %d more", len(differences)-maxIpfsDifferences)
+				break
+			}
+			g.errLog.Println(difference)
+		}
+		return fmt.Errorf("the generated rewards file has %d difference(s) from the canonical file %s", len(differences), cid)
+	}
+	g.log.Printlnf("The generated rewards file matches the canonical file %s field for field.", cid)
+	return nil
+}
+
+// Checks that the generated rewards file serializes to exactly the bytes of the canonical one on IPFS, as a guard against
+// the Smartnode dependency changing the serialization. The performance file's CID is taken from the canonical file, since
+// treegen doesn't reproduce it. On a mismatch, the first differing byte offset is reported with the bytes around it.
+func (g *treeGenerator) checkSmartnodeCompat(rewardsFile rprewards.IRewardsFile) error {
+	canonical, cid, err := g.downloadCanonicalRewardsFile(rewardsFile, "assert-smartnode-compat")
+	if err != nil {
+		return err
+	}
+	var canonicalHeader struct {
+		MinipoolPerformanceFileCID string `json:"minipoolPerformanceFileCid"`
+	}
+	if err := json.Unmarshal(canonical, &canonicalHeader); err != nil {
+		return fmt.Errorf("error parsing the canonical rewards file: %w", err)
+	}
+
+	header := rewardsFile.GetHeader()
+	performanceCid := header.MinipoolPerformanceFileCID
+	rewardsFile.SetMinipoolPerformanceFileCID(canonicalHeader.MinipoolPerformanceFileCID)
+	generated, err := json.Marshal(rewardsFile)
+	rewardsFile.SetMinipoolPerformanceFileCID(performanceCid)
+	if err != nil {
+		return fmt.Errorf("error serializing rewards file into JSON: %w", err)
+	}
+
+	if bytes.Equal(canonical, generated) {
+		g.log.Printlnf("The generated rewards file is byte for byte identical to the canonical file %s.", cid)
+		return nil
+	}
+	offset := 0
+	for offset < len(canonical) && offset < len(generated) && canonical[offset] == generated[offset] {
+		offset++
+	}
+	g.errLog.Printlnf("Canonical bytes at %d: %q", offset, byteContext(canonical, offset))
+	g.errLog.Printlnf("Generated bytes at %d: %q", offset, byteContext(generated, offset))
+	return fmt.Errorf("the generated rewards file (%d bytes) differs from the canonical file %s (%d bytes) starting at byte %d", len(generated), cid, len(canonical), offset)
+}
+
+// Gets up to 40 bytes on either side of an offset, for showing where two files diverge
+func byteContext(data []byte, offset int) []byte {
+	start := offset - 40
+	if start < 0 {
+		start = 0
+	}
+	end := offset + 40
+	if end > len(data) {
+		end = len(data)
+	}
+	if start > end {
+		start = end
+	}
+	return data[start:end]
+}
+
+// Downloads the canonical rewards file for the generated interval by the CID in its rewards event.
+// Only full intervals with an on-chain event have one; flag names the option that needs it.
+func (g *treeGenerator) downloadCanonicalRewardsFile(rewardsFile rprewards.IRewardsFile, flag string) ([]byte, string, error) {
+	if g.targets.rewardsEvent == nil || g.rewardsEventOverride != nil {
+		return nil, "", fmt.Errorf("%s needs the on-chain rewards event of a full interval to know the canonical file's CID", flag)
+	}
+	cid := g.targets.rewardsEvent.MerkleTreeCID
+	filename, _ := g.outputPaths("", rewardsFile.GetHeader().Index)
+	url := fmt.Sprintf("%s/ipfs/%s/%s%s", strings.TrimSuffix(g.ipfsGateway, "/"), cid, filename, config.RewardsTreeIpfsExtension)
+
+	canonical, err := downloadIpfsRewardsFile(url, g.ipfsGatewayTimeout)
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading the canonical rewards file from %s: %w", url, err)
+	}
+	return canonical, cid, nil
+}
+
+// Fetches and decompresses a rewards file uploaded the way the Oracle DAO does, as zstd.
+// The download gets its own timeout rather than http.DefaultClient's, which is --bn-timeout.
+func downloadIpfsRewardsFile(url string, timeout time.Duration) ([]byte, error) {
+	response, err := (&http.Client{Timeout: timeout}).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway returned %s", response.Status)
+	}
+	compressed, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating zstd decompressor: %w", err)
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(compressed, nil)
+}
+
+// Parses JSON without converting numbers to floats, so large values compare exactly
+func decodeJsonNumbers(data []byte, value *interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(value)
+}
+
+// Collects every path at which two decoded JSON values differ
+func diffJsonValues(path string, canonical interface{}, generated interface{}, differences *[]string) {
+	canonicalFields, canonicalIsObject := canonical.(map[string]interface{})
+	generatedFields, generatedIsObject := generated.(map[string]interface{})
+	if canonicalIsObject && generatedIsObject {
+		keys := map[string]bool{}
+		for key := range canonicalFields {
+			keys[key] = true
+		}
+		for key := range generatedFields {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			canonicalValue, inCanonical := canonicalFields[key]
+			generatedValue, inGenerated := generatedFields[key]
+			switch {
+			case !inCanonical:
+				*differences = append(*differences, fmt.Sprintf("%s/%s: only in the generated file", path, key))
+			case !inGenerated:
+				*differences = append(*differences, fmt.Sprintf("%s/%s: only in the canonical file", path, key))
+			default:
+				diffJsonValues(path+"/"+key, canonicalValue, generatedValue, differences)
+			}
+		}
+		return
+	}
+
+	canonicalItems, canonicalIsArray := canonical.([]interface{})
+	generatedItems, generatedIsArray := generated.([]interface{})
+	if canonicalIsArray && generatedIsArray {
+		if len(canonicalItems) != len(generatedItems) {
+			*differences = append(*differences, fmt.Sprintf("%s: canonical has %d items, generated has %d", path, len(canonicalItems), len(generatedItems)))
+			return
+		}
+		for i := range canonicalItems {
+			diffJsonValues(fmt.Sprintf("%s[%d]", path, i), canonicalItems[i], generatedItems[i], differences)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(canonical, generated) {
+		*differences = append(*differences, fmt.Sprintf("%s: canonical %v, generated %v", path, canonical, generated))
+	}
+}
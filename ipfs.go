@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// The response from the IPFS HTTP API's add endpoint
+type ipfsAddResponse struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+	Size string `json:"Size"`
+}
+
+// Adds and pins the interval's written files from the first output directory to IPFS
+func (g *treeGenerator) pinFiles(index uint64) error {
+	rewardsTreePath, minipoolPerformancePath := g.outputPaths(g.outputDirs[0], index)
+	paths := []string{minipoolPerformancePath, rewardsTreePath}
+	if g.onlyMinipoolPerformance {
+		paths = paths[:1]
+	}
+	for _, path := range paths {
+		cid, err := addToIpfs(g.ipfsApi, path, g.ipfsApiTimeout)
+		if err != nil {
+			return fmt.Errorf("error pinning %s to IPFS: %w", path, err)
+		}
+		g.log.Printlnf("Pinned %s to IPFS with CID %s", path, cid)
+	}
+
+	return nil
+}
+
+// Uploads a file to an IPFS node via its HTTP API, pins it, and returns its CID
+func addToIpfs(apiUrl string, path string, timeout time.Duration) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// Build the multipart request body
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", fmt.Errorf("error creating request body: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("error reading file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("error creating request body: %w", err)
+	}
+
+	// Send it
+	url := fmt.Sprintf("%s/api/v0/add?pin=true", strings.TrimSuffix(apiUrl, "/"))
+	// Large uploads need longer than the BN requests that http.DefaultClient is tuned for
+	response, err := (&http.Client{Timeout: timeout}).Post(url, writer.FormDataContentType(), body)
+	if err != nil {
+		return "", fmt.Errorf("error contacting the IPFS API at %s: %w", apiUrl, err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading IPFS API response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("IPFS API returned status %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	var added ipfsAddResponse
+	if err := json.Unmarshal(responseBody, &added); err != nil {
+		return "", fmt.Errorf("error parsing IPFS API response: %w", err)
+	}
+	return added.Hash, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// A node's Merkle leaf and the values it was built from
+type leafRow struct {
+	node    common.Address
+	network uint64
+	rpl     *big.Int
+	eth     *big.Int
+	hash    common.Hash
+}
+
+// Writes every Merkle leaf as CSV, in the order the tree hashes them: by leaf hash, before the zero-hash padding.
+// Each leaf hash is keccak256(node ++ network ++ rpl ++ eth) with the numbers as 32-byte big-endian words,
+// so an external verifier can rebuild the tree from this file alone. Amounts are in wei.
+// This must run before any transform removes nodes from the file.
+func (g *treeGenerator) writeLeavesCsv(rewardsFile rprewards.IRewardsFile, path string) error {
+	rows := []leafRow{}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		leaf := merkleLeaf(address, info)
+		if leaf == nil {
+			continue
+		}
+		rows = append(rows, leafRow{
+			node:    address,
+			network: info.GetRewardNetwork(),
+			rpl:     big.NewInt(0).Add(&info.GetCollateralRpl().Int, &info.GetOracleDaoRpl().Int),
+			eth:     &info.GetSmoothingPoolEth().Int,
+			hash:    crypto.Keccak256Hash(leaf),
+		})
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("the rewards file has no leaves")
+	}
+	sortLeafRows(rows)
+
+	// Make sure the order really is the tree's, since the whole point of the file is to rebuild it
+	if tree := rewardsFile.GetHeader().MerkleTree; tree != nil {
+		height := bits.Len(uint(len(rows) - 1))
+		nodes := tree.Pollard(height)
+		treeLeaves := nodes[1<<height-1:]
+		for i, row := range rows {
+			if row.hash != common.BytesToHash(treeLeaves[i]) {
+				return fmt.Errorf("leaf %d (node %s) doesn't match the Merkle tree's leaf at that position", i, row.node.Hex())
+			}
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	err := writer.Write([]string{"index", "node", "network", "rpl", "eth", "leafHash"})
+	if err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+	for i, row := range rows {
+		err = writer.Write([]string{
+			fmt.Sprint(i),
+			row.node.Hex(),
+			fmt.Sprint(row.network),
+			row.rpl.String(),
+			row.eth.String(),
+			row.hash.Hex(),
+		})
+		if err != nil {
+			return fmt.Errorf("error writing CSV row for node %s: %w", row.node.Hex(), err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+
+	err = writeFileAtomic(path, buffer.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("error saving leaves to %s: %w", path, err)
+	}
+	g.log.Printlnf("Saved the %d Merkle leaves to %s", len(rows), path)
+	g.audit.addOutput(path)
+	return nil
+}
+
+// Sorts leaves by hash, which is the order the tree is built in
+func sortLeafRows(rows []leafRow) {
+	sort.Slice(rows, func(i, j int) bool {
+		return bytes.Compare(rows[i].hash.Bytes(), rows[j].hash.Bytes()) < 0
+	})
+}
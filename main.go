@@ -1,16 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"runtime"
 	"runtime/pprof"
+	"runtime/trace"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 )
 
 const (
-	version    string = "1.5.0"
+	version string = "1.5.0"
+)
+
+// Cleared by --no-color, NO_COLOR, or non-TTY output
+var (
 	colorReset string = "\033[0m"
 	colorRed   string = "\033[31m"
 )
@@ -47,19 +54,89 @@ func main() {
 		&cli.StringFlag{
 			Name:    "ec-endpoint",
 			Aliases: []string{"e"},
-			Usage:   "The URL of the Execution Client's JSON-RPC API. Note that for past interval generation, this must be an Archive EC.",
+			Usage:   "The URL of the Execution Client's JSON-RPC API. http(s)://, ws(s)://, and IPC paths are supported. Note that for past interval generation, this must be an Archive EC.",
 			Value:   "http://localhost:8545",
 		},
 		&cli.StringFlag{
 			Name:    "bn-endpoint",
 			Aliases: []string{"b"},
-			Usage:   "The URL of the Beacon Node's REST API. Note that for past interval generation, this must have Archive capability (ability to replay arbitrary historical states).",
+			Usage:   "The URL of the Beacon Node's REST API, or the path (or unix:// URL) of a Unix socket it serves the API on. Note that for past interval generation, this must have Archive capability (ability to replay arbitrary historical states).",
 			Value:   "http://localhost:5052",
 		},
+		&cli.BoolFlag{
+			Name:  "low-memory",
+			Usage: "Trade speed for a smaller peak heap by running the garbage collector more often and returning freed memory to the OS after the state fetch and tree generation. The full network state still has to be held in memory, since the generator needs all of it at once.",
+			Value: false,
+		},
+		&cli.IntFlag{
+			Name:  "gc-percent",
+			Usage: "Set the Go garbage collector's target heap growth, as debug.SetGCPercent does, and log the peak heap memory at the end of the run. Lower values trade CPU time for a smaller peak heap; Go's default is 100, or the GOGC environment variable if set. Cannot be combined with --low-memory.",
+		},
+		&cli.IntFlag{
+			Name:  "max-concurrent-requests",
+			Usage: "The maximum number of EC requests in flight at once, across treegen and the network state fetch. Lower this if the EC or OS runs out of connections or file descriptors on large backfills.",
+			Value: MaxConcurrentEth1Requests,
+		},
+		&cli.Uint64Flag{
+			Name:  "resume-from-slot",
+			Usage: "Reserved for resuming an interrupted network state fetch. Currently a no-op that only warns, since the state manager fetches the state in a single pass and has no partial progress to resume.",
+		},
+		&cli.IntFlag{
+			Name:  "parallel-state-fetch",
+			Usage: "The maximum number of BN requests in flight at once during the network state fetch. Higher values speed the fetch up but risk overwhelming the BN; the Smartnode never runs more than 12 at a time, so this can only lower that. 0 keeps its default.",
+			Value: 0,
+		},
+		&cli.DurationFlag{
+			Name:  "ec-timeout",
+			Usage: "Timeout for each request to the EC, e.g. 30s. Only supported for HTTP EC endpoints. If unset, requests never time out.",
+		},
+		&cli.StringFlag{
+			Name:  "user-agent",
+			Usage: "The User-Agent header sent with requests to the EC and BN, so operators can identify treegen in their logs. Not sent on WebSocket EC connections.",
+			Value: "treegen/" + version,
+		},
+		&cli.DurationFlag{
+			Name:  "bn-timeout",
+			Usage: "Timeout for each request to the BN, e.g. 10m. Historical state requests can be slow, so this can be set much higher than --ec-timeout. If unset, requests never time out.",
+		},
+		&cli.StringFlag{
+			Name:  "smartnode-config",
+			Usage: "Path to a Smartnode user-settings.yml to read the EC and BN endpoints and network from. Explicit -e and -b flags take precedence. Locally managed clients are reached on localhost, so their RPC ports must be open.",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-experimental-networks",
+			Usage: "Allow overrides that only make sense on custom devnets and test deployments, such as --slots-per-epoch, --seconds-per-slot, and --genesis-time.",
+			Value: false,
+		},
+		&cli.Uint64Flag{
+			Name:  "slots-per-epoch",
+			Usage: "Override the BN's reported slots per epoch for devnets with nonstandard timing. Only rulesets v5 and later honor it, so older ones are rejected. Requires --allow-experimental-networks.",
+		},
+		&cli.Uint64Flag{
+			Name:  "seconds-per-slot",
+			Usage: "Override the BN's reported seconds per slot for devnets with nonstandard timing. Only rulesets v5 and later honor it, so older ones are rejected. Requires --allow-experimental-networks.",
+		},
+		&cli.Uint64Flag{
+			Name:  "genesis-time",
+			Usage: "Override the BN's reported genesis time, in unix seconds, for devnets whose BN misreports it. treegen's own slot to time conversions and rulesets v5 and later use it; older rulesets read the BN's config directly, so they are rejected. Requires --allow-experimental-networks.",
+		},
+		&cli.StringFlag{
+			Name:  "deployment",
+			Usage: "Path to a JSON file mapping contract names to addresses, for forks and testnets with a nonstandard Rocket Pool deployment. Only rocketStorage can be set, since every other contract is resolved through it; e.g. {\"rocketStorage\": \"0x...\"}.",
+		},
 		&cli.StringFlag{
 			Name:    "output-dir",
 			Aliases: []string{"o"},
-			Usage:   "Output directory to save generated files.",
+			Usage:   "Output directory to save generated files. Pass a comma-separated list to save the same files to several directories.",
+		},
+		&cli.BoolFlag{
+			Name:  "output-dir-per-network",
+			Usage: "Save the generated files to a subdirectory of each --output-dir named after the network (e.g. mainnet or prater), creating it if needed, so several networks can be archived under one directory.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "smartnode-layout",
+			Usage: "Path to a Smartnode data directory, e.g. ~/.rocketpool/data. The rewards files are saved to its rewards-trees folder with the names its claim flow reads, in addition to any --output-dir.",
 		},
 		&cli.BoolFlag{
 			Name:    "pretty-print",
@@ -72,17 +149,146 @@ func main() {
 			Aliases: []string{"t"},
 			Usage:   "If provided, this flag will be used to override the last epoch of an interval, current or past. If passed with -i, the epoch must be part of the provided interval.",
 		},
+		&cli.Uint64Flag{
+			Name:  "target-slot",
+			Usage: "If provided, targets this exact slot instead of the last proposed block of an epoch. The slot must have a proposed block. Follows the same rules as -t and cannot be combined with it.",
+		},
+		&cli.Uint64Flag{
+			Name:  "target-el-block",
+			Usage: "If provided, targets the beacon block whose execution payload is this EL block instead of the last block of an epoch. Follows the same rules as -t and cannot be combined with it.",
+		},
+		&cli.StringFlag{
+			Name:  "target-date",
+			Usage: "If provided, targets the last proposed block at or before the end of this UTC day (YYYY-MM-DD) instead of the last block of an epoch. Follows the same rules as -t and cannot be combined with it or the other target flags.",
+		},
+		&cli.Uint64Flag{
+			Name:  "checkpoints-back",
+			Usage: "If provided, targets the block at the finalized checkpoint this many epochs before the latest one, per the BN's head; 0 is the latest finalized checkpoint. Follows the same rules as -t, so it can't reach back before the interval start, and cannot be combined with it or the other target flags.",
+		},
+		&cli.Uint64Flag{
+			Name:  "max-slot-scan",
+			Usage: "The most slots at the end of an epoch to check when looking for its last proposed block, to bound the lookups on a slow BN. If none of them has a block, treegen fails instead of reporting the epoch as empty, since only a full scan can tell it had no proposals. Values of 0 or at least an epoch scan the whole epoch.",
+		},
+		&cli.StringFlag{
+			Name:  "start-time",
+			Usage: "Debugging aid that overrides the interval start time (RFC3339 or unix seconds) instead of reading it from the chain, e.g. to reproduce an old tree after on-chain parameters changed. The start time also feeds the intervals-passed calculation for partial intervals.",
+		},
+		&cli.DurationFlag{
+			Name:  "interval-time",
+			Usage: "Debugging aid that overrides the on-chain rewards interval time, e.g. 672h, when deriving the intervals passed for partial intervals and rewards events built from the command line. Use it to reproduce an interval from before the parameter changed; full intervals take their intervals passed from the rewards event.",
+		},
+		&cli.StringFlag{
+			Name:  "end-time",
+			Usage: "If provided, pins the end time of a dry run (-i -1) to this value instead of the time of the latest finalized block, so repeated runs produce identical files. Accepts an RFC3339 timestamp or unix seconds. The snapshot is taken at the last proposed block at or before this time. Cannot be combined with -t.",
+		},
+		&cli.StringFlag{
+			Name:   "now",
+			Usage:  "Pins the wall-clock time recorded in the audit log and metrics file, as an RFC3339 timestamp or unix seconds, for golden-file tests. Use --end-time to pin a dry run's snapshot.",
+			Hidden: true,
+		},
 		&cli.Uint64Flag{
 			Name:    "ruleset",
 			Aliases: []string{"r"},
 			Usage:   "The ruleset to use during generation. If not included, treegen will use the default ruleset for the network based on the rewards interval at the chosen block. Default of 0 will use whatever the ruleset specified by the network based on which block is being targeted.",
 		},
+		&cli.StringFlag{
+			Name:  "compare-rulesets",
+			Usage: "Generate the targeted interval under two rulesets, given as A,B, from a single state fetch and print the difference in roots, totals, and each node's rewards instead of writing the files.",
+		},
+		&cli.Uint64Flag{
+			Name:  "simulate-ruleset-upgrade",
+			Usage: "Forecast activating this ruleset: generate the current interval's partial tree under its default ruleset and under this one, and print each node's change, how many nodes gain or lose, and the shifts in the totals instead of writing the files.",
+		},
+		&cli.StringFlag{
+			Name:  "events-cache",
+			Usage: "A JSON file to cache rewards submission events in. Events found by scanning the EC's logs are saved to it, and later runs load them from it instead of scanning again, which helps on slow or rate-limited RPCs.",
+		},
+		&cli.BoolFlag{
+			Name:  "refresh-events-cache",
+			Usage: "Ignore the existing contents of --events-cache and rebuild it from the EC's logs.",
+			Value: false,
+		},
+		&cli.Uint64Flag{
+			Name:  "consensus-block",
+			Usage: "The consensus block of the interval given with -i. With --execution-block, --interval-start, and --interval-end, this replaces the interval's rewards event so generation works when the event can't be looked up. The canonical root isn't known this way; use --expected-root to check it.",
+		},
+		&cli.Uint64Flag{
+			Name:  "execution-block",
+			Usage: "The EL block of the interval given with -i, used with --consensus-block instead of the rewards event.",
+		},
+		&cli.StringFlag{
+			Name:  "interval-start",
+			Usage: "The start time of the interval given with -i as an RFC3339 timestamp or unix seconds, used with --consensus-block instead of the rewards event.",
+		},
+		&cli.StringFlag{
+			Name:  "interval-end",
+			Usage: "The end time of the interval given with -i as an RFC3339 timestamp or unix seconds, used with --consensus-block instead of the rewards event.",
+		},
+		&cli.BoolFlag{
+			Name:  "hypothetical-window",
+			Usage: "Generate a hypothetical tree for the window from --interval-start to --interval-end instead of a real interval, counting it as one interval passed under the rules of the interval it ends in. The blocks are resolved from the times. The result is NOT canonical, won't match any on-chain submission, and won't replace an existing file for that interval.",
+			Value: false,
+		},
+		&cli.Uint64Flag{
+			Name:  "interval-start-slot",
+			Usage: "The first slot of the interval given with -i, used with --consensus-block. If unset, it's derived from the previous interval's rewards event.",
+		},
+		&cli.BoolFlag{
+			Name:  "nearest-el-block",
+			Usage: "If the snapshot beacon block has no execution payload, use the EL block of the closest earlier beacon block that has one (up to an epoch back) instead of estimating the EL block from the interval end time.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "beacon-fallback-to-el-time",
+			Usage: "If a post-Merge snapshot beacon block has no execution payload, estimate the EL block from the interval end time as is done before the Merge. Without this or --nearest-el-block, such a block is an error. Pre-Merge snapshots always use the time.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "allow-syncing",
+			Usage: "Continue even if the EC reports that it's still syncing. Historical state calls on a syncing EC can fail or return wrong data, so only use this if you know the blocks being queried are available.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Fail instead of warning if a pre-Merge snapshot's EL block, which is found by time, isn't within an epoch before the snapshot beacon slot. Post-Merge, an EL block whose time doesn't match its slot always fails the run. Also fails instead of warning if --check-totals finds duplicate nodes or Merkle leaves, or if the BN is optimistically synced.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-mismatch",
+			Usage: "Exit with status 2 if the generated Merkle root doesn't match the canonical one, after the files are written; other failures still exit with 1. Combine with --summary-only for a verification job that stays quiet on success.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "el-block-hash",
+			Usage: "The hash of the snapshot EL block. The block is loaded by hash instead of by number and must be the CL snapshot block's payload, which guards against generating on a different fork than the canonical submission.",
+		},
+		&cli.StringFlag{
+			Name:  "expected-root",
+			Usage: "If provided, exit with an error unless the generated tree's Merkle root matches this hash. The files are still written so a mismatch can be inspected. This does not need the on-chain rewards event, so it also works for partial intervals.",
+		},
+		&cli.StringFlag{
+			Name:  "reformat",
+			Usage: "Re-serialize an existing rewards file with the given --pretty-print and --schema-version into --output-dir and exit, without connecting to the EC or BN.",
+		},
+		&cli.Uint64Flag{
+			Name:  "schema-version",
+			Usage: "The rewards file schema version to write, for consumers pinned to an older format. Only downgrades from the version the ruleset produces are supported; v1 files will have no smoothing pool eligibility rates, and their minipool performance ETH amounts are rounded to floats. Default of 0 uses the ruleset's version.",
+		},
 		&cli.BoolFlag{
 			Name:    "network-info",
 			Aliases: []string{"n"},
 			Usage:   "If provided, this will simply print out info about the network being used, the current or targeted interval, and the current or targeted ruleset.",
 			Value:   false,
 		},
+		&cli.BoolFlag{
+			Name:  "print-eligible-nodes",
+			Usage: "Fetch the network state, print roughly how many nodes and minipools will be in the tree, and exit without generating it. Nodes count if they have a staking minipool and either an effective RPL stake or a Smoothing Pool opt-in; attestation performance isn't checked.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "roster",
+			Usage: "Export every node's address, withdrawal address, and minipool validator pubkeys at the snapshot to this file and exit, without generating a tree. A .csv path writes one row per validator; any other path writes JSON.",
+		},
 		&cli.BoolFlag{
 			Name:    "approximate-only",
 			Aliases: []string{"a"},
@@ -95,21 +301,353 @@ func main() {
 			Usage:   "Enable the rolling record capability of the Smartnode tree generator. Use this to store and load record caches instead of recalculating attestation performance each time you run treegen.",
 			Value:   false,
 		},
+		&cli.BoolFlag{
+			Name:  "warn-validator-issues",
+			Usage: "Warn about Rocket Pool validators that are slashed or exiting / exited at the snapshot, since these are common causes of unexpected reward changes. Individual validators are listed with --verbose.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "log-intervals-passed",
+			Usage: "Log the raw time since the interval start, the interval time, and the resulting intervals passed for a partial interval, to help reproduce trees around interval rollovers.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "debug-timing",
+			Usage: "Log every intermediate value of the interval time math: the genesis time and slot timing, the snapshot block time, the start time, interval time, time since the start, intervals passed, and end time, and whether each was overridden. Useful for root mismatches around interval boundaries.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "print-config",
+			Usage: "Print the effective value of every flag, plus the resolved endpoints and network, as JSON before generation begins. Endpoint credentials, paths, and queries are redacted.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Log per-item details (such as individual validators, or the missed slots skipped while resolving the snapshot block) that are otherwise only summarized.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "watch",
+			Usage: "Run until interrupted, generating and saving the tree for each new interval as soon as its rewards submission appears on-chain.",
+			Value: false,
+		},
+		&cli.DurationFlag{
+			Name:  "watch-interval",
+			Usage: "How often --watch polls the current reward index.",
+			Value: 5 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:  "root-only",
+			Usage: "Generate the tree and print its Merkle root, along with whether it matches the canonical root, without serializing or writing any files.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "only-minipool-performance",
+			Usage: "Write only the minipool performance file. The full state is still fetched and the tree is still generated, since the performance data comes from it, but the rewards tree is not serialized or written.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "performance-csv",
+			Usage: "Also export the minipool performance data as CSV next to the performance file, with one row per minipool: address, node, pubkey, expected / successful / missed attestations, and ETH earned (wei).",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "rpl-stakes",
+			Usage: "Also write <network>-<index>-rpl-stakes.json with each node's RPL stake, effective RPL stake, and minimum / maximum stake at the snapshot, plus a note on how the interval's ruleset applies them.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "report",
+			Usage: "Also write a plain text report of the interval to this path, with its dates, snapshot blocks, ruleset, total rewards by category, the top nodes by RPL and ETH, and any warnings, for transparency posts.",
+		},
+		&cli.IntFlag{
+			Name:  "report-top",
+			Usage: "The number of top nodes to list in each table of --report.",
+			Value: 10,
+		},
+		&cli.BoolFlag{
+			Name:  "snapshot-info",
+			Usage: "Also write <network>-<index>-snapshot.json, recording the Beacon slot and EL block used as the interval's snapshot along with their timestamps.",
+			Value: false,
+		},
+		&cli.IntFlag{
+			Name:  "max-invalid-networks",
+			Usage: "Fail the run if more than this many nodes have an invalid reward network; up to this many are only warned about. A negative value has no limit.",
+			Value: -1,
+		},
+		&cli.Int64Flag{
+			Name:  "oldest-supported-interval",
+			Usage: "Fail up front if --interval is older than this. Negative uses the known oldest interval for the network: 0 on Mainnet and 7 on Prater, whose earliest intervals predate the rewards snapshot event.",
+			Value: -1,
+		},
+		&cli.Uint64Flag{
+			Name:  "max-intervals-passed",
+			Usage: "Fail if a snapshot works out that more than this many intervals have passed since the interval start, which almost always means a misconfigured start time or clock. 0 has no limit.",
+			Value: 10,
+		},
+		&cli.BoolFlag{
+			Name:  "reward-split",
+			Usage: "Print how the interval's RPL split between node operators, the Oracle DAO, and the Protocol DAO, and how its Smoothing Pool ETH split between node operators and rETH stakers, in wei and percent. Included in the --summary-only report.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "reward-split-file",
+			Usage: "Path to which to save the reward split from --reward-split as JSON.",
+		},
+		&cli.BoolFlag{
+			Name:  "check-totals",
+			Usage: "After generating the tree, check that the per-node RPL and Smoothing Pool ETH add up to the interval's totals, overall and per reward network, and fail on any difference beyond a wei of rounding per node. Also warns if a node is listed twice or two nodes share a Merkle leaf, or fails with --strict.",
+			Value: false,
+		},
+		&cli.Uint64Flag{
+			Name:  "verify-proofs-sample",
+			Usage: "After generating the tree, verify the Merkle proofs of this many randomly chosen nodes against the root, and fail if any don't verify. 0 disables the check.",
+			Value: 0,
+		},
+		&cli.BoolFlag{
+			Name:  "print-tree-stats",
+			Usage: "After generating the tree, print its number of leaves, depth, and distinct reward networks, plus the min / median / max RPL and ETH per node.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "estimate-sizes",
+			Usage: "Log the size of both output files, computed from their in-memory serialization, before writing them to disk.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "print-hashes",
+			Usage: "Log the SHA-256 of the rewards tree and minipool performance files after writing them, so the output of two runs can be compared without diffing.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "pin",
+			Usage: "After the files are written, add and pin both of them to the IPFS node at --ipfs-api and print their CIDs.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "ipfs-api",
+			Usage: "The URL of the IPFS node's HTTP API used by --pin.",
+			Value: "http://localhost:5001",
+		},
+		&cli.DurationFlag{
+			Name:  "ipfs-api-timeout",
+			Usage: "Timeout for each upload to --ipfs-api by --pin. Raise it for slow links, since a rewards file can be tens of megabytes.",
+			Value: 10 * time.Minute,
+		},
+		&cli.StringFlag{
+			Name:  "node-summary",
+			Usage: "After generating the tree, print this node's collateral RPL, Oracle DAO RPL, and Smoothing Pool ETH for the interval, with its RPL stake, effective stake, and minipool counts at the snapshot, as JSON.",
+		},
+		&cli.StringFlag{
+			Name:  "attestation-detail",
+			Usage: "After generating the tree, print the successful and missed attestations and the missed slots that were counted for each of this node's minipools as JSON, for resolving Smoothing Pool reward disputes.",
+		},
+		&cli.StringFlag{
+			Name:  "claim-calldata",
+			Usage: "After generating the tree, print the calldata for this node to claim its rewards for the interval from the Merkle distributor, as a hex blob to send from the node address. The node must have rewards on network 0. Only useful for a full interval whose root is on chain.",
+		},
+		&cli.BoolFlag{
+			Name:  "compare-ipfs",
+			Usage: "After generating a full interval, download the canonical rewards file from --ipfs-gateway by the CID in the interval's rewards event and fail if any field differs from the generated file.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "assert-smartnode-compat",
+			Usage: "After the --compare-ipfs field comparison, which this implies, also fail unless the generated rewards file serializes to exactly the canonical file's bytes, and print the first differing byte offset. Use on a known past interval after updating the Smartnode dependency.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "ipfs-gateway",
+			Usage: "The IPFS gateway used by --compare-ipfs and --assert-smartnode-compat.",
+			Value: "https://ipfs.io",
+		},
+		&cli.DurationFlag{
+			Name:  "ipfs-gateway-timeout",
+			Usage: "Timeout for downloading the canonical rewards file from --ipfs-gateway. Public gateways can take minutes to fetch an uncached file.",
+			Value: 10 * time.Minute,
+		},
+		&cli.StringFlag{
+			Name:  "node-filter",
+			Usage: "A file with one node address per line, or a comma-separated list of node addresses. The full tree is still generated, but only these nodes are written to the rewards file. The Merkle root and proofs still refer to the full tree, so the output is for inspection only and cannot be used to claim rewards.",
+		},
+		&cli.BoolFlag{
+			Name:  "omit-zero-rewards",
+			Usage: "Drop nodes with no RPL or ETH rewards from the rewards file. They have no Merkle leaf so the root is unchanged, but the file will no longer match the canonical one; use this only for slimmed-down analysis exports.",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "anonymize",
+			Usage: "Replace each node address in the rewards file with a keyed hash of it, so the file can be shared for debugging. Amounts and proofs are kept, but the output cannot be used to claim rewards. The file is sorted by pseudonym, so the order of the real addresses is not kept.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "anonymize-salt",
+			Usage: "The secret key for --anonymize's hashes. Pass the same one to give nodes the same pseudonyms across runs; by default a random key is used, so pseudonyms differ every run. Keep it private, since anyone with it can reverse the mapping.",
+		},
+		&cli.BoolFlag{
+			Name:  "no-proofs",
+			Usage: "Leave the per-node Merkle proofs out of the rewards tree file, which makes it much smaller for analytics. The result is NOT claimable. The amounts, networks, and root are kept.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "dump-merkle-tree",
+			Usage: "Path to which to save the full Merkle tree as JSON, so any proof can be checked without rebuilding it. \"levels\" lists the hashes level by level from the root (level i has 2^i hashes) down to the leaves, which are sorted and padded with zero hashes to a power of two.",
+		},
+		&cli.StringFlag{
+			Name:  "dump-leaves-csv",
+			Usage: "Path to which to save every Merkle leaf as CSV (index, node, network, rpl and eth in wei, leaf hash) in the order the tree hashes them, so external verifiers can rebuild the tree. Cannot be combined with --anonymize.",
+		},
+		&cli.StringFlag{
+			Name:  "previous-deltas",
+			Usage: "Path to which to save a CSV of each node's change in RPL and ETH (in wei) since the previous interval, including nodes that were added or dropped. The previous interval's rewards file is read from the first output directory unless --previous-rewards-file is set.",
+		},
+		&cli.StringFlag{
+			Name:  "previous-rewards-file",
+			Usage: "Path to the previous interval's rewards file for --previous-deltas.",
+		},
+		&cli.StringFlag{
+			Name:  "split-proofs",
+			Usage: "After generation, also write one small file per node to this directory, named by its address and holding just its amounts, Merkle leaf, and proof, plus an index.json with the tree's root and metadata. Node operators can then fetch only their own file.",
+		},
+		&cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "Path to a JSONL file to which a record of each generation is appended: timestamp, interval, ruleset, Merkle root, whether it matched the canonical root, duration, output paths, and any error. The file is created if it doesn't exist.",
+		},
+		&cli.BoolFlag{
+			Name:  "benchmark",
+			Usage: "Time each phase of the run (client dial, config fetch, state fetch, tree generation, serialization, and file writes) and print a breakdown at the end.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "metrics-file",
+			Usage: "Write the phase timings from --benchmark plus allocation counts, heap size, and GC pauses to this file as JSON when the run finishes, for tracking performance across runs.",
+		},
+		&cli.BoolFlag{
+			Name:  "summary-only",
+			Usage: "Suppress the step-by-step log and print a single report at the end of each generation with the interval, root, canonical match, totals, durations, output files, and warnings.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "run-id",
+			Usage: "An ID for this run, such as an externally assigned correlation ID, to prefix every log line with and record in the audit log and summary. A random 8-character hex ID is used if this isn't set.",
+		},
+		&cli.BoolFlag{
+			Name:  "no-color",
+			Usage: "Disable colored output. Color is also disabled automatically when stdout is not a terminal or the NO_COLOR environment variable is set.",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  "validate-file",
+			Usage: "Path to an existing rewards tree file to verify offline instead of generating one. The Merkle tree is rebuilt from the file's node entries and checked against its embedded root and proofs; no EC or BN is needed.",
+		},
+		&cli.StringFlag{
+			Name:  "warnings-file",
+			Usage: "Path to which to save every warning raised during the run as JSON, each with a stable code such as ROOT_MISMATCH or INVALID_NETWORK_NODE, so log processors can alert on them. Written even if the run fails.",
+		},
+		&cli.StringFlag{
+			Name:  "block-map",
+			Usage: "Path to which to save every slot treegen looked up on the BN, with its time, whether it was proposed, and its EL block, for debugging snapshot block resolution around missed slots. The format follows the extension: .csv writes CSV, anything else JSON. Written even if the run fails.",
+		},
+		&cli.StringFlag{
+			Name:  "targets-file",
+			Usage: "Path to a JSON list of {\"ec\", \"bn\", \"outputDir\"} targets to generate for one after another, e.g. mainnet and a testnet. Each target's network is detected from its BN, and a failing target doesn't stop the others. Replaces --ec-endpoint, --bn-endpoint, and --output-dir.",
+		},
+		&cli.StringFlag{
+			Name:  "claimable-for",
+			Usage: "A withdrawal address to total the RPL and ETH earned by its nodes, per interval and overall, across the rewards files in --rewards-dir instead of generating a tree. Needs --roster-file for the node to withdrawal address mapping; no EC or BN is needed.",
+		},
+		&cli.StringFlag{
+			Name:  "rewards-dir",
+			Usage: "The directory of existing rewards tree files scanned by --claimable-for.",
+		},
+		&cli.StringFlag{
+			Name:  "roster-file",
+			Usage: "A roster written by --roster, used by --claimable-for to find the nodes of a withdrawal address. Prefer the JSON format, since the CSV one omits nodes without minipools.",
+		},
 		&cli.StringFlag{
 			Name:    "cpuprofile",
 			Aliases: []string{"c"},
 			Usage:   "Path to which to save a pprof cpu profile, e.g. ./treegen.pprof. If unset, profiling is disabled.",
 		},
+		&cli.BoolFlag{
+			Name:  "profile-generation-only",
+			Usage: "Narrow the --cpuprofile window to the state fetch and tree generation, excluding client setup and file writes.",
+			Value: false,
+		},
 		&cli.StringFlag{
 			Name:    "memprofile",
 			Aliases: []string{"m"},
 			Usage:   "Path to which to save a pprof heap profile, e.g. ./treegen.pprof. If unset, profiling is disabled.",
 		},
+		&cli.StringFlag{
+			Name:  "trace",
+			Usage: "Path to which to save a runtime execution trace, e.g. ./treegen.trace, to be inspected with `go tool trace`. If unset, tracing is disabled.",
+		},
+	}
+
+	app.Commands = []*cli.Command{
+		{
+			Name:   "healthcheck",
+			Usage:  "Check that the EC and BN are reachable, on a known network, and able to serve historical state, and report the current reward index and time until the next interval, without generating a tree. Exits nonzero if any check fails.",
+			Action: Healthcheck,
+		},
+		{
+			Name:   "endpoint-health",
+			Usage:  "Report how far back the EC and BN retain historical state, by binary searching for the oldest EL block and slot each can serve state for. The search assumes state is kept for every block from that point onward.",
+			Action: EndpointHealth,
+		},
+		{
+			Name:   "next-interval",
+			Usage:  "Print when the current interval's snapshot is due and how long is left, without generating a tree. Only the EC is queried for rewards data.",
+			Action: NextInterval,
+		},
+		{
+			Name:      "perf-diff",
+			Usage:     "Compare two minipool performance files and print every minipool whose attestations, missed slots, or ETH earned differ as JSON, without connecting to the EC or BN.",
+			ArgsUsage: "<old file> <new file>",
+			Action:    PerfDiff,
+		},
+		{
+			Name:  "serve",
+			Usage: "Run an HTTP service where GET /tree?interval=N returns the rewards tree for a submitted interval, generating it on demand with the usual flags (given before \"serve\"). Trees are cached in the first output directory, and only one is generated at a time.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "listen",
+					Usage: "The address to listen on.",
+					Value: "localhost:8080",
+				},
+				&cli.IntFlag{
+					Name:  "max-pending-requests",
+					Usage: "How many requests may wait for or run a generation at once; further requests for uncached trees get a 503.",
+					Value: 4,
+				},
+			},
+			Action: Serve,
+		},
+	}
+
+	app.Before = func(c *cli.Context) error {
+		// fatih/color already honors NO_COLOR and non-TTY stdout; extend that to the flag and the raw ANSI codes
+		if c.Bool("no-color") {
+			color.NoColor = true
+		}
+		if color.NoColor {
+			colorReset = ""
+			colorRed = ""
+		}
+		if err := setupRunId(c); err != nil {
+			return err
+		}
+		return nil
 	}
 
 	app.Action = func(c *cli.Context) error {
+		// With --profile-generation-only, the generator starts and stops the cpu profile itself
 		cpuprofile := c.String("cpuprofile")
-		if cpuprofile != "" {
+		if c.Bool("profile-generation-only") && cpuprofile == "" {
+			return fmt.Errorf("profile-generation-only requires cpuprofile")
+		}
+		if cpuprofile != "" && !c.Bool("profile-generation-only") {
 			f, err := os.Create(cpuprofile)
 			if err != nil {
 				fmt.Printf("%sError generating tree: %s%s\n", colorRed, err.Error(), colorReset)
@@ -126,29 +664,75 @@ func main() {
 		memprofile := c.String("memprofile")
 		if memprofile != "" {
 			defer func() {
-				f, err := os.Create(memprofile)
-				if err != nil {
-					fmt.Printf("%sError saving heap profile: %w%w\n", colorRed, err, colorReset)
+				if err := writeHeapProfile(memprofile); err != nil {
+					fmt.Printf("%sError saving heap profile: %s%s\n", colorRed, err.Error(), colorReset)
 					os.Exit(1)
 				}
-				defer f.Close()
-				runtime.GC()
-				if err := pprof.WriteHeapProfile(f); err != nil {
-					fmt.Printf("%sError saving heap profile: %w%w\n", colorRed, err, colorReset)
-				}
 			}()
 		}
 
+		tracefile := c.String("trace")
+		if tracefile != "" {
+			f, err := os.Create(tracefile)
+			if err != nil {
+				fmt.Printf("%sError generating tree: %s%s\n", colorRed, err.Error(), colorReset)
+				os.Exit(1)
+			}
+			defer f.Close()
+			if err := trace.Start(f); err != nil {
+				fmt.Printf("%sError generating tree: %s%s\n", colorRed, err.Error(), colorReset)
+				os.Exit(1)
+			}
+			defer trace.Stop()
+		}
+
+		// Deferred calls don't run when the process is killed, so flush the profiles from a signal handler too
+		if cpuprofile != "" || memprofile != "" || tracefile != "" {
+			defer flushProfilesOnInterrupt(memprofile)()
+		}
+
+		// These modes only read existing files, so they run without connecting to the EC or BN
+		if c.String("validate-file") != "" {
+			return ValidateFile(c)
+		}
+		if c.String("reformat") != "" {
+			return ReformatFile(c)
+		}
+		if c.String("claimable-for") != "" {
+			return SumClaimable(c)
+		}
+
+		if c.String("targets-file") != "" {
+			return GenerateTargets(c)
+		}
 		return GenerateTree(c)
 	}
 
 	// Run application
-	fmt.Println("")
+	// The surrounding blank lines are only for terminals, so piped output like --print-config stays machine-readable
+	interactive := isTerminal(os.Stdout)
+	if interactive {
+		fmt.Println("")
+	}
 	err := app.Run(os.Args)
 	if err != nil {
 		fmt.Printf("%sError generating tree: %s%s\n", colorRed, err.Error(), colorReset)
+		if errors.Is(err, errCanonicalMismatch) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
-	fmt.Println("")
+	if interactive {
+		fmt.Println("")
+	}
 
 }
+
+// Checks whether a file is an interactive terminal rather than a pipe or regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+)
+
+// The GC target used by --low-memory, as a percentage of the live heap; Go's default is 100
+const lowMemoryGCPercent = 25
+
+// Makes the GC run more often so the heap stays closer to what's actually live, at the cost of more CPU time.
+// The Smartnode's state manager and tree generator need the whole network state at once, so this can only trim the
+// garbage around it rather than stream validators through in chunks.
+func enableLowMemory() {
+	debug.SetGCPercent(lowMemoryGCPercent)
+}
+
+// Sets the GC target for --gc-percent
+func setGCPercent(percent int) {
+	debug.SetGCPercent(percent)
+}
+
+// Returns memory freed by the previous phase to the OS if --low-memory is set
+func (g *treeGenerator) releaseMemory() {
+	if g.lowMemory {
+		debug.FreeOSMemory()
+	}
+}
+
+// Logs the most heap memory the run obtained from the OS, the same stand-in for peak memory that --metrics-file uses.
+// The Go runtime keeps heap memory it has freed for a while, so this is close to the peak heap.
+func logPeakMemory(logger *log.ColorLogger, gcPercent int) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	logger.Printlnf("Peak heap memory: %s with a GC target of %d%%", formatSize(int(stats.HeapSys)), gcPercent)
+}
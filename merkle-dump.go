@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/common"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// The full Merkle tree for --dump-merkle-tree.
+// Levels runs from the root down: level 0 is the root alone, level i holds 2^i hashes, and the last level is the leaves.
+// The leaves are the keccak256 hashes of the node leaves, sorted by hash and padded with zero hashes up to a power of two,
+// and each branch is the hash of its two children in sorted order, as the Smartnode builds it.
+type merkleTreeDump struct {
+	Interval  uint64          `json:"interval"`
+	Root      common.Hash     `json:"root"`
+	LeafCount int             `json:"leafCount"`
+	Levels    [][]common.Hash `json:"levels"`
+}
+
+// Saves every level of the rewards file's Merkle tree, so proofs can be checked without rebuilding it.
+// This must run before any transform removes nodes from the file, since the leaf count is taken from it.
+func (g *treeGenerator) writeMerkleTree(rewardsFile rprewards.IRewardsFile, path string) error {
+	header := rewardsFile.GetHeader()
+	if header.MerkleTree == nil {
+		return fmt.Errorf("the rewards file has no Merkle tree to export")
+	}
+
+	// Only nodes with rewards have a leaf
+	leafCount := 0
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		if merkleLeaf(address, info) != nil {
+			leafCount++
+		}
+	}
+	if leafCount == 0 {
+		return fmt.Errorf("the rewards file has no leaves")
+	}
+
+	// The tree's levels sit in one array in order from the root, so the pollard down to the leaves is all of them
+	height := bits.Len(uint(leafCount - 1))
+	nodes := header.MerkleTree.Pollard(height)
+	dump := merkleTreeDump{
+		Interval:  header.Index,
+		Root:      common.BytesToHash(header.MerkleTree.Root()),
+		LeafCount: leafCount,
+		Levels:    make([][]common.Hash, 0, height+1),
+	}
+	for level := 0; level <= height; level++ {
+		start := 1<<level - 1
+		hashes := make([]common.Hash, 0, 1<<level)
+		for _, node := range nodes[start : start+1<<level] {
+			hashes = append(hashes, common.BytesToHash(node))
+		}
+		dump.Levels = append(dump.Levels, hashes)
+	}
+
+	bytes, err := g.serializeJson(dump)
+	if err != nil {
+		return fmt.Errorf("error serializing Merkle tree into JSON: %w", err)
+	}
+	err = writeFileAtomic(path, bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error saving Merkle tree to %s: %w", path, err)
+	}
+	g.log.Printlnf("Saved the %d levels of the Merkle tree to %s", len(dump.Levels), path)
+	g.audit.addOutput(path)
+	return nil
+}
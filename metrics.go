@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// The timing and resource usage of a run, written to --metrics-file for tracking performance across runs
+type runMetrics struct {
+	Timestamp         time.Time          `json:"timestamp"`
+	TotalSeconds      float64            `json:"totalSeconds"`
+	PhaseSeconds      map[string]float64 `json:"phaseSeconds"`
+	Mallocs           uint64             `json:"mallocs"`
+	TotalAllocBytes   uint64             `json:"totalAllocBytes"`
+	PeakHeapBytes     uint64             `json:"peakHeapBytes"`
+	NumGC             uint32             `json:"numGC"`
+	GCPauseSeconds    float64            `json:"gcPauseSeconds"`
+	MaxGCPauseSeconds float64            `json:"maxGCPauseSeconds"`
+}
+
+// Writes the phase timings and the process's memory statistics to the given file, stamped with the time the run started.
+// The Go runtime doesn't track the peak live heap, so the heap memory obtained from the OS stands in for it.
+func writeMetrics(path string, timer *phaseTimer, start time.Time, startedAt time.Time) error {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	metrics := runMetrics{
+		Timestamp:       startedAt.UTC(),
+		TotalSeconds:    time.Since(start).Seconds(),
+		PhaseSeconds:    map[string]float64{},
+		Mallocs:         stats.Mallocs,
+		TotalAllocBytes: stats.TotalAlloc,
+		PeakHeapBytes:   stats.HeapSys,
+		NumGC:           stats.NumGC,
+		GCPauseSeconds:  time.Duration(stats.PauseTotalNs).Seconds(),
+	}
+	// The runtime only keeps the most recent 256 pauses
+	for _, pause := range stats.PauseNs {
+		if seconds := time.Duration(pause).Seconds(); seconds > metrics.MaxGCPauseSeconds {
+			metrics.MaxGCPauseSeconds = seconds
+		}
+	}
+	for _, phase := range timer.phases {
+		metrics.PhaseSeconds[phase.name] = phase.duration.Seconds()
+	}
+
+	bytes, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializing metrics: %w", err)
+	}
+	return writeFileAtomic(path, bytes, 0644)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rocket-pool/rocketpool-go/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// Prints when the current interval's snapshot is due and how long is left, from the on-chain interval start and
+// interval time. Only a couple of contract calls on the EC are needed.
+func NextInterval(c *cli.Context) error {
+	// Configure
+	configureHTTP()
+	logger := log.NewColorLogger(color.FgHiWhite)
+
+	conn, err := connect(c, &logger, nil)
+	if err != nil {
+		return fmt.Errorf("connection check failed: %w", err)
+	}
+
+	index, err := rewards.GetRewardIndex(conn.rp, nil)
+	if err != nil {
+		return fmt.Errorf("error getting current reward index: %w", err)
+	}
+	startTime, err := rewards.GetClaimIntervalTimeStart(conn.rp, nil)
+	if err != nil {
+		return fmt.Errorf("error getting claim interval start time: %w", err)
+	}
+	intervalTime, err := rewards.GetClaimIntervalTime(conn.rp, nil)
+	if err != nil {
+		return fmt.Errorf("error getting claim interval time: %w", err)
+	}
+	if intervalTime <= 0 {
+		return fmt.Errorf("the claim interval time is %s, so there is no next interval", intervalTime)
+	}
+
+	// The snapshot is taken at the first boundary after the start; the Oracle DAO may submit it some time later
+	boundary := startTime.Add(intervalTime)
+	remaining := time.Until(boundary)
+	logger.Printlnf("Interval %d started at %s and lasts %s.", index.Uint64(), startTime.UTC().Format(time.RFC3339), intervalTime)
+	if remaining > 0 {
+		logger.Printlnf("Its snapshot is due at %s, in %s.", boundary.UTC().Format(time.RFC3339), remaining.Round(time.Second))
+	} else {
+		logger.Printlnf("Its snapshot was due at %s, %s ago, and is waiting for the Oracle DAO to submit it.", boundary.UTC().Format(time.RFC3339), (-remaining).Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/goccy/go-json"
+	"github.com/rocket-pool/rocketpool-go/types"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/services/state"
+)
+
+// Everything a node earned in the interval, with the snapshot details that decided it
+type nodeSummary struct {
+	Node                    common.Address          `json:"node"`
+	Interval                uint64                  `json:"interval"`
+	RewardNetwork           uint64                  `json:"rewardNetwork"`
+	CollateralRpl           *rprewards.QuotedBigInt `json:"collateralRpl"`
+	OracleDaoRpl            *rprewards.QuotedBigInt `json:"oracleDaoRpl"`
+	SmoothingPoolEth        *rprewards.QuotedBigInt `json:"smoothingPoolEth"`
+	RplStake                *rprewards.QuotedBigInt `json:"rplStake"`
+	EffectiveRplStake       *rprewards.QuotedBigInt `json:"effectiveRplStake"`
+	Minipools               int                     `json:"minipools"`
+	StakingMinipools        int                     `json:"stakingMinipools"`
+	SmoothingPoolMinipools  int                     `json:"smoothingPoolMinipools"`
+	SmoothingPoolRegistered bool                    `json:"smoothingPoolRegistered"`
+	OracleDaoMember         bool                    `json:"oracleDaoMember"`
+}
+
+// Prints a summary of one node's rewards in the generated file and its stake and minipools at the snapshot as JSON,
+// to answer what the node got and why without reading the whole rewards file.
+// smoothingPoolMinipools counts the node's minipools in the performance file, i.e. those that earned Smoothing Pool ETH.
+func (g *treeGenerator) printNodeSummary(rewardsFile rprewards.IRewardsFile, networkState *state.NetworkState, node common.Address) error {
+	details, exists := networkState.NodeDetailsByAddress[node]
+	if !exists {
+		return fmt.Errorf("node %s is not registered at slot %d", node.Hex(), networkState.BeaconSlotNumber)
+	}
+
+	summary := nodeSummary{
+		Node:                    node,
+		Interval:                rewardsFile.GetHeader().Index,
+		RewardNetwork:           details.RewardNetwork.Uint64(),
+		CollateralRpl:           &rprewards.QuotedBigInt{},
+		OracleDaoRpl:            &rprewards.QuotedBigInt{},
+		SmoothingPoolEth:        &rprewards.QuotedBigInt{},
+		RplStake:                quotedBigInt(details.RplStake),
+		EffectiveRplStake:       quotedBigInt(details.EffectiveRPLStake),
+		SmoothingPoolRegistered: details.SmoothingPoolRegistrationState,
+	}
+	if info, exists := rewardsFile.GetNodeRewardsInfo(node); exists {
+		summary.RewardNetwork = info.GetRewardNetwork()
+		summary.CollateralRpl = info.GetCollateralRpl()
+		summary.OracleDaoRpl = info.GetOracleDaoRpl()
+		summary.SmoothingPoolEth = info.GetSmoothingPoolEth()
+	}
+
+	perfFile := rewardsFile.GetMinipoolPerformanceFile()
+	for _, mpd := range networkState.MinipoolDetailsByNode[node] {
+		summary.Minipools++
+		if mpd.Status == types.Staking && !mpd.Finalised {
+			summary.StakingMinipools++
+		}
+		if _, exists := perfFile.GetSmoothingPoolPerformance(mpd.MinipoolAddress); exists {
+			summary.SmoothingPoolMinipools++
+		}
+	}
+	for _, member := range networkState.OracleDaoMemberDetails {
+		if member.Address == node {
+			summary.OracleDaoMember = true
+			break
+		}
+	}
+
+	output, err := json.MarshalIndent(summary, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error serializing node summary into JSON: %w", err)
+	}
+	g.log.Printlnf("Summary for node %s in interval %d:", node.Hex(), summary.Interval)
+	fmt.Println(string(output))
+	return nil
+}
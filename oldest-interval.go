@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	cfgtypes "github.com/rocket-pool/smartnode/shared/types/config"
+)
+
+// The oldest interval treegen can generate on each network by looking up its rewards events.
+// Prater's first six intervals predate the snapshot event, and an interval's start slot comes from the previous interval's event,
+// so interval 7 is the first one where both events exist. The Smartnode's hardcoded copies of those early events aren't exported.
+var oldestSupportedIntervals = map[cfgtypes.Network]uint64{
+	cfgtypes.Network_Mainnet: 0,
+	cfgtypes.Network_Prater:  7,
+}
+
+// Fails up front if the requested interval is older than the oldest one that can be generated.
+// A negative override uses the known value for the network.
+func (g *treeGenerator) checkOldestSupportedInterval(interval uint64, override int64) error {
+	network := g.cfg.Smartnode.Network.Value.(cfgtypes.Network)
+	oldest, known := oldestSupportedIntervals[network]
+	if override >= 0 {
+		oldest, known = uint64(override), true
+	}
+	if !known || interval >= oldest {
+		return nil
+	}
+	return fmt.Errorf("interval %d is older than the oldest supported interval %d on %s; to generate it anyway, provide its rewards event with --%s and --interval-start-slot", interval, oldest, network, strings.Join(manualEventFlags, ", --"))
+}
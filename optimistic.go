@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// The part of the BN's /eth/v1/node/syncing response that reports optimistic sync
+type syncingResponse struct {
+	Data struct {
+		IsOptimistic bool `json:"is_optimistic"`
+	} `json:"data"`
+}
+
+// Checks whether the BN is optimistically synced, i.e. following blocks its EC hasn't validated yet.
+// The Smartnode's BN client doesn't expose this, so the standard API endpoint is queried directly.
+func isBnOptimistic(bnUrl string) (bool, error) {
+	response, err := http.Get(strings.TrimSuffix(bnUrl, "/") + "/eth/v1/node/syncing")
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("the BN returned status %s", response.Status)
+	}
+
+	var syncing syncingResponse
+	if err := json.NewDecoder(response.Body).Decode(&syncing); err != nil {
+		return false, fmt.Errorf("error parsing the BN's sync status: %w", err)
+	}
+	return syncing.Data.IsOptimistic, nil
+}
+
+// Warns, or fails if --strict is set, when the BN's view of the chain hasn't been fully validated.
+// The check is advisory, so a BN that can't answer it only gets a warning.
+func (g *treeGenerator) checkBnOptimistic() error {
+	optimistic, err := isBnOptimistic(g.bnApiUrl)
+	if err != nil {
+		g.warn(warningOptimisticUnchecked, "couldn't check whether the BN is optimistically synced: %s", err.Error())
+		return nil
+	}
+	if !optimistic {
+		return nil
+	}
+	if g.strict {
+		return fmt.Errorf("the BN is optimistically synced, so its finalized chain hasn't been validated by its EC yet; wait for the EC to catch up")
+	}
+	g.warn(warningOptimisticBn, "the BN is optimistically synced, so its finalized chain hasn't been validated by its EC yet. The resulting tree may not be trustworthy.")
+	return nil
+}
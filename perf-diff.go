@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	"github.com/goccy/go-json"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// A minipool's performance in one of the compared files
+type minipoolPerformanceValues struct {
+	SuccessfulAttestations  uint64 `json:"successfulAttestations"`
+	MissedAttestations      uint64 `json:"missedAttestations"`
+	MissingAttestationSlots int    `json:"missingAttestationSlots"`
+	EthEarned               string `json:"ethEarned"`
+}
+
+// How a minipool's performance differs between two files; old or new is missing if the minipool is only in one of them
+type minipoolPerformanceDiff struct {
+	Minipool        common.Address             `json:"minipool"`
+	Status          string                     `json:"status"`
+	Old             *minipoolPerformanceValues `json:"old,omitempty"`
+	New             *minipoolPerformanceValues `json:"new,omitempty"`
+	MissedOnlyInOld []uint64                   `json:"missedOnlyInOld,omitempty"`
+	MissedOnlyInNew []uint64                   `json:"missedOnlyInNew,omitempty"`
+}
+
+// Compares two minipool performance files and prints every minipool whose performance differs as JSON, ordered by address
+func PerfDiff(c *cli.Context) error {
+	logger := log.NewColorLogger(color.FgHiWhite)
+	if c.NArg() != 2 {
+		return fmt.Errorf("perf-diff takes the old and new minipool performance file paths")
+	}
+
+	oldFile, err := loadMinipoolPerformanceFile(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	newFile, err := loadMinipoolPerformanceFile(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	// Every minipool in either file
+	addresses := map[common.Address]bool{}
+	for _, address := range oldFile.GetMinipoolAddresses() {
+		addresses[address] = true
+	}
+	for _, address := range newFile.GetMinipoolAddresses() {
+		addresses[address] = true
+	}
+	sorted := make([]common.Address, 0, len(addresses))
+	for address := range addresses {
+		sorted = append(sorted, address)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Bytes(), sorted[j].Bytes()) < 0
+	})
+
+	diffs := []minipoolPerformanceDiff{}
+	var added, removed, changed int
+	for _, address := range sorted {
+		oldPerformance, inOld := oldFile.GetSmoothingPoolPerformance(address)
+		newPerformance, inNew := newFile.GetSmoothingPoolPerformance(address)
+		diff := minipoolPerformanceDiff{Minipool: address}
+		switch {
+		case !inOld:
+			diff.Status = "added"
+			diff.New = newPerformanceValues(newPerformance)
+			added++
+		case !inNew:
+			diff.Status = "removed"
+			diff.Old = newPerformanceValues(oldPerformance)
+			removed++
+		default:
+			diff.MissedOnlyInOld = slotsMissing(oldPerformance.GetMissingAttestationSlots(), newPerformance.GetMissingAttestationSlots())
+			diff.MissedOnlyInNew = slotsMissing(newPerformance.GetMissingAttestationSlots(), oldPerformance.GetMissingAttestationSlots())
+			if len(diff.MissedOnlyInOld) == 0 && len(diff.MissedOnlyInNew) == 0 &&
+				oldPerformance.GetSuccessfulAttestationCount() == newPerformance.GetSuccessfulAttestationCount() &&
+				oldPerformance.GetMissedAttestationCount() == newPerformance.GetMissedAttestationCount() &&
+				oldPerformance.GetEthEarned().Cmp(newPerformance.GetEthEarned()) == 0 {
+				continue
+			}
+			diff.Status = "changed"
+			diff.Old = newPerformanceValues(oldPerformance)
+			diff.New = newPerformanceValues(newPerformance)
+			changed++
+		}
+		diffs = append(diffs, diff)
+	}
+
+	output, err := json.MarshalIndent(diffs, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error serializing the differences into JSON: %w", err)
+	}
+	fmt.Println(string(output))
+	logger.Printlnf("%d minipool(s) added, %d removed, and %d with changed performance.", added, removed, changed)
+	return nil
+}
+
+// Reads a minipool performance file of any version
+func loadMinipoolPerformanceFile(path string) (rprewards.IMinipoolPerformanceFile, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	file, err := rprewards.DeserializeMinipoolPerformanceFile(bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error deserializing %s: %w", path, err)
+	}
+	return file, nil
+}
+
+func newPerformanceValues(performance rprewards.ISmoothingPoolMinipoolPerformance) *minipoolPerformanceValues {
+	return &minipoolPerformanceValues{
+		SuccessfulAttestations:  performance.GetSuccessfulAttestationCount(),
+		MissedAttestations:      performance.GetMissedAttestationCount(),
+		MissingAttestationSlots: len(performance.GetMissingAttestationSlots()),
+		EthEarned:               performance.GetEthEarned().String(),
+	}
+}
+
+// Gets the slots in the first list that aren't in the second, in order
+func slotsMissing(slots []uint64, other []uint64) []uint64 {
+	inOther := map[uint64]bool{}
+	for _, slot := range other {
+		inOther[slot] = true
+	}
+	missing := []uint64{}
+	for _, slot := range slots {
+		if !inOther[slot] {
+			missing = append(missing, slot)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
+}
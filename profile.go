@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"syscall"
+)
+
+// Starts the CPU profile for the generation phase if --profile-generation-only was passed.
+// The returned function stops it and is safe to call more than once.
+func (g *treeGenerator) startGenerationProfile() (func(), error) {
+	if g.generationProfile == "" {
+		return func() {}, nil
+	}
+
+	f, err := os.Create(g.generationProfile)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cpu profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error starting cpu profile: %w", err)
+	}
+
+	stopped := false
+	return func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		pprof.StopCPUProfile()
+		f.Close()
+	}, nil
+}
+
+// Writes a heap profile to the given path after a GC, so it reflects live memory
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
+
+// Stops the CPU profile and trace and writes the heap profile if the process gets SIGINT or SIGTERM, then exits.
+// The returned function removes the handler once the run finishes normally.
+func flushProfilesOnInterrupt(memprofile string) func() {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-interrupt:
+			fmt.Printf("Received %s, flushing profiles before exiting.\n", sig)
+			pprof.StopCPUProfile()
+			trace.Stop()
+			if memprofile != "" {
+				if err := writeHeapProfile(memprofile); err != nil {
+					fmt.Printf("%sError saving heap profile: %s%s\n", colorRed, err.Error(), colorReset)
+				}
+			}
+			os.Exit(128 + int(sig.(syscall.Signal)))
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(interrupt)
+		close(done)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Checks the embedded Merkle proofs of a random sample of nodes against the tree's root, the way the distributor
+// contract does when a node claims. A matching root alone doesn't prove the proofs are right.
+func (g *treeGenerator) checkProofsSample(rewardsFile rprewards.IRewardsFile, sampleSize uint64) error {
+	header := rewardsFile.GetHeader()
+	root := header.MerkleTree.Root()
+
+	// Only nodes with rewards have a leaf to prove
+	addresses := []common.Address{}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		if merkleLeaf(address, info) != nil {
+			addresses = append(addresses, address)
+		}
+	}
+	if uint64(len(addresses)) < sampleSize {
+		sampleSize = uint64(len(addresses))
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	random.Shuffle(len(addresses), func(i, j int) {
+		addresses[i], addresses[j] = addresses[j], addresses[i]
+	})
+
+	failed := 0
+	for _, address := range addresses[:sampleSize] {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		proof, err := info.GetMerkleProof()
+		if err != nil {
+			g.warn(warningInvalidProof, "node %s has an unreadable Merkle proof: %s", address.Hex(), err.Error())
+			failed++
+			continue
+		}
+		if !verifyProof(merkleLeaf(address, info), proof, root) {
+			g.warn(warningInvalidProof, "node %s has a Merkle proof that doesn't verify against the root %s", address.Hex(), common.BytesToHash(root).Hex())
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sampled Merkle proofs failed to verify", failed, sampleSize)
+	}
+	g.log.Printlnf("All %d sampled Merkle proofs verify against the root.", sampleSize)
+	return nil
+}
+
+// Walks a proof from a leaf up to the root, hashing each pair with the lower hash first
+func verifyProof(leaf []byte, proof []common.Hash, root []byte) bool {
+	hash := crypto.Keccak256(leaf)
+	for _, sibling := range proof {
+		if bytes.Compare(hash, sibling.Bytes()) <= 0 {
+			hash = crypto.Keccak256(hash, sibling.Bytes())
+		} else {
+			hash = crypto.Keccak256(sibling.Bytes(), hash)
+		}
+	}
+	return bytes.Equal(hash, root)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rocket-pool/smartnode/shared/services/config"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// Re-serializes an existing rewards file with the requested --pretty-print and --schema-version, saving it under its
+// canonical name in each output directory. The tree itself is untouched, so this needs no EC or BN.
+func ReformatFile(c *cli.Context) error {
+	logger := log.NewColorLogger(color.FgHiWhite)
+	path := c.String("reformat")
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	rewardsFile, err := rprewards.DeserializeRewardsFile(bytes)
+	if err != nil {
+		return fmt.Errorf("error deserializing %s: %w", path, err)
+	}
+	header := rewardsFile.GetHeader()
+	logger.Printlnf("Loaded rewards file v%d for interval %d on %s", header.RewardsFileVersion, header.Index, header.Network)
+
+	schemaVersion := c.Uint64("schema-version")
+	if schemaVersion > latestRewardsFileVersion {
+		return fmt.Errorf("unsupported schema-version %d; supported versions are 1 through %d", schemaVersion, latestRewardsFileVersion)
+	}
+	if schemaVersion != 0 && schemaVersion != header.RewardsFileVersion {
+		rewardsFile, err = convertRewardsFile(rewardsFile, schemaVersion)
+		if err != nil {
+			return fmt.Errorf("error converting rewards file to schema version %d: %w", schemaVersion, err)
+		}
+		logger.Printlnf("Converted the rewards file from schema version %d to %d.", header.RewardsFileVersion, schemaVersion)
+		header = rewardsFile.GetHeader()
+	}
+
+	g := &treeGenerator{log: &logger, prettyPrint: c.Bool("pretty-print")}
+	bytes, err = g.serializeRewardsTree(rewardsFile)
+	if err != nil {
+		return fmt.Errorf("error serializing rewards file into JSON: %w", err)
+	}
+	for _, outputDir := range strings.Split(c.String("output-dir"), ",") {
+		if err := checkDirWritable(outputDir); err != nil {
+			return err
+		}
+		rewardsTreePath := filepath.Join(outputDir, fmt.Sprintf(config.RewardsTreeFilenameFormat, header.Network, header.Index))
+		err = writeFileAtomic(rewardsTreePath, bytes, 0644)
+		if err != nil {
+			return fmt.Errorf("error saving rewards tree file to %s: %w", rewardsTreePath, err)
+		}
+		logger.Printlnf("Saved rewards snapshot file to %s", rewardsTreePath)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Writes a plain text summary of the generated interval to the given path, for publishing alongside the JSON files
+func (g *treeGenerator) writeReport(rewardsFile rprewards.IRewardsFile, path string) error {
+	header := rewardsFile.GetHeader()
+	report := &strings.Builder{}
+
+	fmt.Fprintf(report, "Rocket Pool Rewards Interval %d (%s)\n", header.Index, header.Network)
+	fmt.Fprintf(report, "%s\n\n", strings.Repeat("=", 40))
+	fmt.Fprintf(report, "Period:           %s to %s\n", header.StartTime.UTC().Format(time.RFC1123), header.EndTime.UTC().Format(time.RFC1123))
+	fmt.Fprintf(report, "Intervals passed: %d\n", header.IntervalsPassed)
+	fmt.Fprintf(report, "Beacon slots:     %d to %d\n", header.ConsensusStartBlock, header.ConsensusEndBlock)
+	fmt.Fprintf(report, "EL blocks:        %d to %d\n", header.ExecutionStartBlock, header.ExecutionEndBlock)
+	fmt.Fprintf(report, "Ruleset:          v%d\n", header.RulesetVersion)
+	fmt.Fprintf(report, "Merkle root:      %s\n\n", header.MerkleRoot)
+
+	if totals := header.TotalRewards; totals != nil {
+		fmt.Fprintf(report, "Total Rewards\n")
+		fmt.Fprintf(report, "  Node operator collateral: %16.6f RPL\n", eth.WeiToEth(totalAmount(totals.TotalCollateralRpl)))
+		fmt.Fprintf(report, "  Oracle DAO:               %16.6f RPL\n", eth.WeiToEth(totalAmount(totals.TotalOracleDaoRpl)))
+		fmt.Fprintf(report, "  Protocol DAO:             %16.6f RPL\n", eth.WeiToEth(totalAmount(totals.ProtocolDaoRpl)))
+		fmt.Fprintf(report, "  Smoothing Pool:           %16.6f ETH\n", eth.WeiToEth(totalAmount(totals.TotalSmoothingPoolEth)))
+		fmt.Fprintf(report, "    to node operators:      %16.6f ETH\n", eth.WeiToEth(totalAmount(totals.NodeOperatorSmoothingPoolEth)))
+		fmt.Fprintf(report, "    to rETH stakers:        %16.6f ETH\n\n", eth.WeiToEth(totalAmount(totals.PoolStakerSmoothingPoolEth)))
+	}
+
+	addresses := rewardsFile.GetNodeAddresses()
+	fmt.Fprintf(report, "Nodes rewarded: %d\n\n", len(addresses))
+	writeTopNodes(report, fmt.Sprintf("Top %d Nodes by RPL", g.reportTop), addresses, g.reportTop, func(address common.Address) *big.Int {
+		rpl, _ := nodeRewards(rewardsFile, address)
+		return rpl
+	}, "RPL")
+	writeTopNodes(report, fmt.Sprintf("Top %d Nodes by ETH", g.reportTop), addresses, g.reportTop, func(address common.Address) *big.Int {
+		_, ethAmount := nodeRewards(rewardsFile, address)
+		return ethAmount
+	}, "ETH")
+
+	if len(g.warnings) > 0 {
+		fmt.Fprintf(report, "Warnings\n")
+		for _, warning := range g.warnings {
+			fmt.Fprintf(report, "  - [%s] %s\n", warning.Code, warning.Message)
+		}
+	}
+
+	err := writeFileAtomic(path, []byte(report.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("error saving report to %s: %w", path, err)
+	}
+	g.log.Printlnf("Saved report to %s", path)
+	g.audit.addOutput(path)
+	return nil
+}
+
+// Writes a ranked table of the nodes with the largest amounts, skipping nodes with none
+func writeTopNodes(report *strings.Builder, title string, addresses []common.Address, count int, amount func(common.Address) *big.Int, unit string) {
+	ranked := make([]common.Address, 0, len(addresses))
+	amounts := make(map[common.Address]*big.Int, len(addresses))
+	for _, address := range addresses {
+		if value := amount(address); value.Sign() > 0 {
+			ranked = append(ranked, address)
+			amounts[address] = value
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if cmp := amounts[ranked[i]].Cmp(amounts[ranked[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(ranked[i].Bytes(), ranked[j].Bytes()) < 0
+	})
+	if len(ranked) > count {
+		ranked = ranked[:count]
+	}
+
+	fmt.Fprintf(report, "%s\n", title)
+	for i, address := range ranked {
+		fmt.Fprintf(report, "  %3d. %s %16.6f %s\n", i+1, address.Hex(), eth.WeiToEth(amounts[address]), unit)
+	}
+	fmt.Fprintf(report, "\n")
+}
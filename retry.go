@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+)
+
+const (
+	// How many times a transient chain call is attempted before giving up
+	chainCallAttempts = 3
+
+	// The delay before the first retry; it doubles with each attempt
+	chainCallRetryDelay = 2 * time.Second
+
+	// How many times to check whether a lagging EC has imported the snapshot EL block, and how long to wait between checks
+	elBlockWaitAttempts = 10
+	elBlockWaitDelay    = 6 * time.Second
+)
+
+// Runs a chain call until it succeeds or has failed chainCallAttempts times, logging each failed attempt.
+// Returns the last error if every attempt failed.
+func (g *treeGenerator) retry(name string, call func() error) error {
+	return retryCall(g.log, name, call)
+}
+
+// Runs a call with the same retries as the generator's chain calls, for use before the generator exists
+func retryCall(logger *log.ColorLogger, name string, call func() error) error {
+	delay := chainCallRetryDelay
+	var err error
+	for attempt := 1; attempt <= chainCallAttempts; attempt++ {
+		err = call()
+		if err == nil {
+			return nil
+		}
+		if attempt < chainCallAttempts {
+			logger.Printlnf("Error %s (attempt %d of %d), retrying in %s: %s", name, attempt, chainCallAttempts, delay, err.Error())
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// Waits for the EC to import the given EL block, since an EC slightly behind the BN may not have the snapshot block yet.
+// Gives up after elBlockWaitAttempts checks.
+func (g *treeGenerator) waitForElBlock(number *big.Int) error {
+	for attempt := 1; ; attempt++ {
+		head, err := g.rp.Client.BlockNumber(context.Background())
+		if err != nil {
+			return fmt.Errorf("error getting the EC's latest block: %w", err)
+		}
+		if head >= number.Uint64() {
+			return nil
+		}
+		if attempt == elBlockWaitAttempts {
+			return fmt.Errorf("the EC is at block %d and didn't import EL block %s within %s; is it still syncing?", head, number.String(), elBlockWaitDelay*(elBlockWaitAttempts-1))
+		}
+		g.log.Printlnf("The EC is at block %d, behind EL block %s; waiting %s for it to catch up (check %d of %d)...", head, number.String(), elBlockWaitDelay, attempt, elBlockWaitAttempts)
+		time.Sleep(elBlockWaitDelay)
+	}
+}
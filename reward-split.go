@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// An amount and its share of the category total
+type rewardShare struct {
+	Wei     string  `json:"wei"`
+	Percent float64 `json:"percent"`
+}
+
+// Where an interval's RPL and Smoothing Pool ETH went, from the rewards file totals
+type rewardSplit struct {
+	Interval              uint64      `json:"interval"`
+	TotalRpl              string      `json:"totalRpl"`
+	NodeOperatorRpl       rewardShare `json:"nodeOperatorRpl"`
+	OracleDaoRpl          rewardShare `json:"oracleDaoRpl"`
+	ProtocolDaoRpl        rewardShare `json:"protocolDaoRpl"`
+	TotalSmoothingPoolEth string      `json:"totalSmoothingPoolEth"`
+	NodeOperatorEth       rewardShare `json:"nodeOperatorEth"`
+	PoolStakerEth         rewardShare `json:"poolStakerEth"`
+}
+
+// Computes the reward split of a generated file
+func getRewardSplit(header *rprewards.RewardsFileHeader) *rewardSplit {
+	totals := header.TotalRewards
+	if totals == nil {
+		totals = &rprewards.TotalRewards{}
+	}
+
+	totalRpl := big.NewInt(0).Add(totalAmount(totals.TotalCollateralRpl), totalAmount(totals.TotalOracleDaoRpl))
+	totalRpl.Add(totalRpl, totalAmount(totals.ProtocolDaoRpl))
+	totalEth := totalAmount(totals.TotalSmoothingPoolEth)
+	return &rewardSplit{
+		Interval:              header.Index,
+		TotalRpl:              totalRpl.String(),
+		NodeOperatorRpl:       newRewardShare(totalAmount(totals.TotalCollateralRpl), totalRpl),
+		OracleDaoRpl:          newRewardShare(totalAmount(totals.TotalOracleDaoRpl), totalRpl),
+		ProtocolDaoRpl:        newRewardShare(totalAmount(totals.ProtocolDaoRpl), totalRpl),
+		TotalSmoothingPoolEth: totalEth.String(),
+		NodeOperatorEth:       newRewardShare(totalAmount(totals.NodeOperatorSmoothingPoolEth), totalEth),
+		PoolStakerEth:         newRewardShare(totalAmount(totals.PoolStakerSmoothingPoolEth), totalEth),
+	}
+}
+
+// Gets an amount's share of a total; an empty total has no shares
+func newRewardShare(amount *big.Int, total *big.Int) rewardShare {
+	share := rewardShare{Wei: amount.String()}
+	if total.Sign() == 0 {
+		return share
+	}
+	ratio := big.NewFloat(0).Quo(big.NewFloat(0).SetInt(amount), big.NewFloat(0).SetInt(total))
+	share.Percent, _ = ratio.Float64()
+	share.Percent *= 100
+	return share
+}
+
+// Gets the reward split as printable lines
+func (s *rewardSplit) lines() []string {
+	line := func(label string, share rewardShare) string {
+		return fmt.Sprintf("%-26s %s wei (%.4f%%)", label+":", share.Wei, share.Percent)
+	}
+	return []string{
+		line("RPL to node operators", s.NodeOperatorRpl),
+		line("RPL to the Oracle DAO", s.OracleDaoRpl),
+		line("RPL to the Protocol DAO", s.ProtocolDaoRpl),
+		line("SP ETH to node operators", s.NodeOperatorEth),
+		line("SP ETH to rETH stakers", s.PoolStakerEth),
+	}
+}
+
+// Saves the reward split as JSON
+func (g *treeGenerator) writeRewardSplit(split *rewardSplit, path string) error {
+	bytes, err := g.serializeJson(split)
+	if err != nil {
+		return fmt.Errorf("error serializing reward split into JSON: %w", err)
+	}
+	err = writeFileAtomic(path, bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error saving reward split to %s: %w", path, err)
+	}
+	g.log.Printlnf("Saved the reward split to %s", path)
+	g.audit.addOutput(path)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// A node operator's addresses and minipool validators at the snapshot
+type rosterNode struct {
+	NodeAddress       string   `json:"nodeAddress"`
+	WithdrawalAddress string   `json:"withdrawalAddress"`
+	ValidatorPubkeys  []string `json:"validatorPubkeys"`
+}
+
+// Exports every node's address, withdrawal address, and minipool validator pubkeys at the snapshot, without generating a tree.
+// The format follows the file extension: .csv writes one row per validator, anything else writes JSON.
+func (g *treeGenerator) writeRoster(path string) error {
+	args, err := g.getTreegenArgs()
+	if err != nil {
+		return fmt.Errorf("error compiling treegen arguments: %w", err)
+	}
+	networkState := args.state
+
+	nodes := make([]rosterNode, 0, len(networkState.NodeDetails))
+	for _, node := range networkState.NodeDetails {
+		pubkeys := []string{}
+		for _, mpd := range networkState.MinipoolDetailsByNode[node.NodeAddress] {
+			pubkeys = append(pubkeys, mpd.Pubkey.Hex())
+		}
+		sort.Strings(pubkeys)
+		nodes = append(nodes, rosterNode{
+			NodeAddress:       node.NodeAddress.Hex(),
+			WithdrawalAddress: node.WithdrawalAddress.Hex(),
+			ValidatorPubkeys:  pubkeys,
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeAddress < nodes[j].NodeAddress
+	})
+
+	var data []byte
+	if filepath.Ext(path) == ".csv" {
+		buffer := &bytes.Buffer{}
+		writer := csv.NewWriter(buffer)
+		err = writer.Write([]string{"node", "withdrawalAddress", "pubkey"})
+		if err != nil {
+			return fmt.Errorf("error writing CSV header: %w", err)
+		}
+		for _, node := range nodes {
+			for _, pubkey := range node.ValidatorPubkeys {
+				err = writer.Write([]string{node.NodeAddress, node.WithdrawalAddress, pubkey})
+				if err != nil {
+					return fmt.Errorf("error writing CSV row for node %s: %w", node.NodeAddress, err)
+				}
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("error writing CSV: %w", err)
+		}
+		data = buffer.Bytes()
+	} else {
+		data, err = g.serializeJson(nodes)
+		if err != nil {
+			return fmt.Errorf("error serializing roster into JSON: %w", err)
+		}
+	}
+
+	err = writeFileAtomic(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("error saving roster to %s: %w", path, err)
+	}
+	g.log.Printlnf("Saved the roster of %d nodes and %d minipools at slot %d to %s", len(nodes), len(networkState.MinipoolDetails), networkState.BeaconSlotNumber, path)
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	rpstate "github.com/rocket-pool/rocketpool-go/utils/state"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/services/state"
+	cfgtypes "github.com/rocket-pool/smartnode/shared/types/config"
+)
+
+// Explains where the stakes in the export come from and how the ruleset uses them
+const rplStakeNote = "Stakes are read from RocketNodeStaking at the snapshot EL block. effectiveRplStake is the contract's figure: 0 below minimumRplStake and capped at maximumRplStake. Rulesets 1 through 3 reward that figure directly; ruleset 4 and later recompute the minimum and maximum from only the node's minipools that were active at the end of the interval, so a node with pending or exited minipools may be credited with less."
+
+// A rewarded node's RPL stake at the snapshot
+type nodeRplStake struct {
+	Address           common.Address          `json:"address"`
+	RplStake          *rprewards.QuotedBigInt `json:"rplStake"`
+	EffectiveRplStake *rprewards.QuotedBigInt `json:"effectiveRplStake"`
+	MinimumRplStake   *rprewards.QuotedBigInt `json:"minimumRplStake"`
+	MaximumRplStake   *rprewards.QuotedBigInt `json:"maximumRplStake"`
+}
+
+// The --rpl-stakes export for an interval
+type rplStakeFile struct {
+	Network        string         `json:"network"`
+	Index          uint64         `json:"index"`
+	RulesetVersion uint64         `json:"rulesetVersion"`
+	BeaconSlot     uint64         `json:"beaconSlot"`
+	ElBlock        uint64         `json:"elBlock"`
+	Note           string         `json:"note"`
+	Nodes          []nodeRplStake `json:"nodes"`
+}
+
+// Writes the RPL stake of every node in the rewards file, as of the snapshot state, to each output directory
+func (g *treeGenerator) writeRplStakes(rewardsFile rprewards.IRewardsFile, networkState *state.NetworkState) error {
+	header := rewardsFile.GetHeader()
+
+	// The file's addresses may have been anonymized, so match them the same way
+	nodes := make(map[common.Address]*rpstate.NativeNodeDetails, len(networkState.NodeDetails))
+	for i, node := range networkState.NodeDetails {
+		address := node.NodeAddress
+		if g.anonymize {
+			address = anonymizeAddress(g.anonymizeKey, address)
+		}
+		nodes[address] = &networkState.NodeDetails[i]
+	}
+
+	stakes := rplStakeFile{
+		Network:        string(g.cfg.Smartnode.Network.Value.(cfgtypes.Network)),
+		Index:          header.Index,
+		RulesetVersion: header.RulesetVersion,
+		BeaconSlot:     networkState.BeaconSlotNumber,
+		ElBlock:        networkState.ElBlockNumber,
+		Note:           rplStakeNote,
+		Nodes:          make([]nodeRplStake, 0, len(rewardsFile.GetNodeAddresses())),
+	}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		node, exists := nodes[address]
+		if !exists {
+			return fmt.Errorf("node %s is in the rewards file but not in the network state", address.Hex())
+		}
+		stakes.Nodes = append(stakes.Nodes, nodeRplStake{
+			Address:           address,
+			RplStake:          quotedBigInt(node.RplStake),
+			EffectiveRplStake: quotedBigInt(node.EffectiveRPLStake),
+			MinimumRplStake:   quotedBigInt(node.MinimumRPLStake),
+			MaximumRplStake:   quotedBigInt(node.MaximumRPLStake),
+		})
+	}
+
+	bytes, err := g.serializeJson(stakes)
+	if err != nil {
+		return fmt.Errorf("error serializing RPL stakes into JSON: %w", err)
+	}
+	for _, outputDir := range g.outputDirs {
+		path := filepath.Join(outputDir, fmt.Sprintf("%s-%d-rpl-stakes.json", stakes.Network, header.Index))
+		err = writeFileAtomic(path, bytes, 0644)
+		if err != nil {
+			return fmt.Errorf("error saving RPL stakes to %s: %w", path, err)
+		}
+		g.log.Printlnf("Saved RPL stakes to %s", path)
+		g.audit.addOutput(path)
+	}
+
+	return nil
+}
+
+// Wraps an amount for JSON, which the Smartnode writes as a quoted string
+func quotedBigInt(x *big.Int) *rprewards.QuotedBigInt {
+	quoted := &rprewards.QuotedBigInt{}
+	quoted.Set(x)
+	return quoted
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	stdlog "log"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Gives the run an ID, from --run-id or else a random one, and prefixes every log line with it so the output of one
+// run can be picked out of a shared log aggregator. The ID is stored back in the flag so it can be read like any other.
+func setupRunId(c *cli.Context) error {
+	id := c.String("run-id")
+	if id == "" {
+		random := make([]byte, 4)
+		if _, err := rand.Read(random); err != nil {
+			return fmt.Errorf("error generating a run ID: %w", err)
+		}
+		id = hex.EncodeToString(random)
+		if err := c.Set("run-id", id); err != nil {
+			return fmt.Errorf("error setting the run ID: %w", err)
+		}
+	}
+	stdlog.SetPrefix("[" + id + "] ")
+	stdlog.SetFlags(stdlog.LstdFlags | stdlog.Lmsgprefix)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rocket-pool/rocketpool-go/rewards"
+	"github.com/urfave/cli/v2"
+)
+
+// Runs treegen as an HTTP service that generates trees on demand with one set of EC and BN clients.
+// The generator is configured from the usual flags, so this goes through GenerateTree, which starts the server.
+func Serve(c *cli.Context) error {
+	if c.Int("max-pending-requests") < 1 {
+		return fmt.Errorf("max-pending-requests must be at least 1")
+	}
+	return GenerateTree(c)
+}
+
+// Serves generated rewards trees by interval. The first output directory doubles as the cache, so a tree is only
+// generated once, even across restarts.
+type treeServer struct {
+	g *treeGenerator
+
+	// The generator holds the state of the interval it's working on, so only one tree is generated at a time
+	lock sync.Mutex
+
+	// Requests that are waiting for or running a generation; more are turned away rather than piling up on the archive node
+	pending chan struct{}
+}
+
+// Listens for tree requests until the process is interrupted
+func (g *treeGenerator) serve(listen string, maxPending int) error {
+	s := &treeServer{g: g, pending: make(chan struct{}, maxPending)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tree", s.handleTree)
+	server := &http.Server{Addr: listen, Handler: mux}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		g.log.Println("Interrupted, shutting down the server.")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	g.log.Printlnf("Serving rewards trees at http://%s/tree?interval=N", listen)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return fmt.Errorf("error running the server: %w", err)
+}
+
+// Handles GET /tree?interval=N with the interval's rewards tree, generating it first if it isn't cached
+func (s *treeServer) handleTree(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	index, err := strconv.ParseUint(r.URL.Query().Get("interval"), 10, 64)
+	if err != nil {
+		http.Error(w, "interval must be a reward interval index", http.StatusBadRequest)
+		return
+	}
+
+	path, _ := s.g.outputPaths(s.g.outputDirs[0], index)
+	if _, err := os.Stat(path); err == nil {
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	// Only submitted intervals are final, so the current one is never generated or cached
+	currentIndex, err := rewards.GetRewardIndex(s.g.rp, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error getting current reward index: %s", err.Error()), http.StatusBadGateway)
+		return
+	}
+	if index >= currentIndex.Uint64() {
+		http.Error(w, fmt.Sprintf("interval %d hasn't been submitted yet; the current interval is %d", index, currentIndex.Uint64()), http.StatusNotFound)
+		return
+	}
+
+	select {
+	case s.pending <- struct{}{}:
+		defer func() { <-s.pending }()
+	default:
+		http.Error(w, "too many trees are already being generated; try again later", http.StatusServiceUnavailable)
+		return
+	}
+	if err := s.generate(index, path); err != nil {
+		s.g.errLog.Printlnf("Error generating interval %d for %s: %s", index, r.RemoteAddr, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// Generates an interval's tree into the cache, unless a request that held the lock first already did
+func (s *treeServer) generate(index uint64, path string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	s.g.log.Printlnf("Generating the tree for interval %d.", index)
+	s.g.targets = targets{}
+	s.g.recordMgr = nil
+	s.g.warnings = nil
+	if err := s.g.setTargets(int64(index), 0); err != nil {
+		return fmt.Errorf("error setting the targets for interval %d: %w", index, err)
+	}
+	if err := s.g.generateTree(); err != nil {
+		return fmt.Errorf("error generating interval %d: %w", index, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("generating interval %d didn't save a rewards tree to %s", index, path)
+	}
+	return nil
+}
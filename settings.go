@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/goccy/go-json"
+	cfgtypes "github.com/rocket-pool/smartnode/shared/types/config"
+	"github.com/urfave/cli/v2"
+)
+
+// Flags holding URLs that may embed credentials or API keys
+var urlFlags = map[string]bool{
+	"ec-endpoint": true,
+	"bn-endpoint": true,
+	"ipfs-api":    true,
+}
+
+// Prints every flag's effective value, plus the resolved endpoints and network, as JSON
+func printEffectiveConfig(c *cli.Context, conn *connections) error {
+	flags := map[string]interface{}{}
+	for _, flag := range c.App.Flags {
+		name := flag.Names()[0]
+		value := c.Value(name)
+		if urlFlags[name] {
+			value = redactUrl(fmt.Sprint(value))
+		}
+		if duration, ok := value.(time.Duration); ok {
+			value = duration.String()
+		}
+		flags[name] = value
+	}
+
+	settings := map[string]interface{}{
+		"version": version,
+		"network": string(conn.cfg.Smartnode.Network.Value.(cfgtypes.Network)),
+		"chainId": conn.chainID,
+		// These may come from --smartnode-config rather than the flags
+		"ecEndpoint": redactUrl(conn.ecUrl),
+		"bnEndpoint": redactUrl(conn.bnUrl),
+		"flags":      flags,
+	}
+
+	bytes, err := json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error serializing effective config: %w", err)
+	}
+	fmt.Println(string(bytes))
+	return nil
+}
+
+// Keeps only the scheme and host of a URL, since user info, paths, and queries often carry secrets
+func redactUrl(value string) string {
+	if value == "" {
+		return value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return "<redacted>"
+	}
+	redacted := parsed.Scheme + "://" + parsed.Host
+	if parsed.User != nil || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" {
+		redacted += "/<redacted>"
+	}
+	return redacted
+}
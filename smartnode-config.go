@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rocket-pool/smartnode/shared/services/config"
+	cfgtypes "github.com/rocket-pool/smartnode/shared/types/config"
+)
+
+// The EC / BN endpoints and network configured in a Smartnode's user-settings.yml
+type smartnodeSettings struct {
+	ecUrl   string
+	bnUrl   string
+	network cfgtypes.Network
+}
+
+// Loads the client endpoints and network from a Smartnode config file.
+// Locally managed (Docker) clients are assumed to be reachable on localhost, which requires their RPC ports to be open.
+func loadSmartnodeSettings(path string) (*smartnodeSettings, error) {
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("Smartnode config file %s does not exist", path)
+	}
+
+	settings := &smartnodeSettings{
+		network: cfg.Smartnode.Network.Value.(cfgtypes.Network),
+	}
+
+	if cfg.IsNativeMode {
+		settings.ecUrl = cfg.Native.EcHttpUrl.Value.(string)
+		settings.bnUrl = cfg.Native.CcHttpUrl.Value.(string)
+		return settings, nil
+	}
+
+	if cfg.ExecutionClientMode.Value.(cfgtypes.Mode) == cfgtypes.Mode_External {
+		settings.ecUrl = cfg.ExternalExecution.HttpUrl.Value.(string)
+	} else {
+		settings.ecUrl = fmt.Sprintf("http://localhost:%v", cfg.ExecutionCommon.HttpPort.Value)
+	}
+
+	ccConfig, err := cfg.GetSelectedConsensusClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error getting consensus client config: %w", err)
+	}
+	if external, ok := ccConfig.(cfgtypes.ExternalConsensusConfig); ok {
+		settings.bnUrl = external.GetApiUrl()
+	} else {
+		settings.bnUrl = fmt.Sprintf("http://localhost:%v", cfg.ConsensusCommon.ApiPort.Value)
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	cfgtypes "github.com/rocket-pool/smartnode/shared/types/config"
+)
+
+// The anchoring data for a generated interval, written next to the rewards files
+type snapshotSidecar struct {
+	Network         string    `json:"network"`
+	Index           uint64    `json:"index"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	IntervalsPassed uint64    `json:"intervalsPassed"`
+	StartSlot       uint64    `json:"startSlot"`
+	BeaconSlot      uint64    `json:"beaconSlot"`
+	BeaconSlotTime  time.Time `json:"beaconSlotTime"`
+	ElBlock         uint64    `json:"elBlock"`
+	ElBlockTime     time.Time `json:"elBlockTime"`
+	FullInterval    bool      `json:"fullInterval"`
+}
+
+// Gets the path of the snapshot sidecar for an interval in the given directory
+func (g *treeGenerator) snapshotSidecarPath(outputDir string, index uint64) string {
+	network := string(g.cfg.Smartnode.Network.Value.(cfgtypes.Network))
+	return filepath.Join(outputDir, fmt.Sprintf("%s-%d-snapshot.json", network, index))
+}
+
+// Writes the snapshot sidecar for the generated interval to each output directory
+func (g *treeGenerator) writeSnapshotSidecar(args *treegenArguments) error {
+	sidecar := snapshotSidecar{
+		Network:         string(g.cfg.Smartnode.Network.Value.(cfgtypes.Network)),
+		Index:           args.index,
+		StartTime:       args.startTime,
+		EndTime:         args.endTime,
+		IntervalsPassed: args.intervalsPassed,
+		StartSlot:       args.startSlot,
+		ElBlock:         args.elBlockHeader.Number.Uint64(),
+		ElBlockTime:     time.Unix(int64(args.elBlockHeader.Time), 0),
+	}
+
+	// Full intervals are anchored by the rewards event, partial ones by the snapshot details
+	if g.targets.rewardsEvent != nil {
+		sidecar.BeaconSlot = g.targets.rewardsEvent.ConsensusBlock.Uint64()
+		sidecar.FullInterval = true
+	} else {
+		sidecar.BeaconSlot = g.targets.snapshotDetails.snapshotBeaconBlock
+	}
+	sidecar.BeaconSlotTime = g.slotToTime(sidecar.BeaconSlot)
+
+	bytes, err := g.serializeJson(sidecar)
+	if err != nil {
+		return fmt.Errorf("error serializing snapshot sidecar into JSON: %w", err)
+	}
+
+	for _, outputDir := range g.outputDirs {
+		path := g.snapshotSidecarPath(outputDir, args.index)
+		err = writeFileAtomic(path, bytes, 0644)
+		if err != nil {
+			return fmt.Errorf("error saving snapshot sidecar to %s: %w", path, err)
+		}
+		g.log.Printlnf("Saved snapshot sidecar to %s", path)
+		g.audit.addOutput(path)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/goccy/go-json"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// A single node's part of the rewards tree, written by --split-proofs
+type nodeProofFile struct {
+	Address          common.Address          `json:"address"`
+	RewardNetwork    uint64                  `json:"rewardNetwork"`
+	CollateralRpl    *rprewards.QuotedBigInt `json:"collateralRpl"`
+	OracleDaoRpl     *rprewards.QuotedBigInt `json:"oracleDaoRpl"`
+	SmoothingPoolEth *rprewards.QuotedBigInt `json:"smoothingPoolEth"`
+	Leaf             string                  `json:"leaf"`
+	MerkleProof      []common.Hash           `json:"merkleProof"`
+}
+
+// Writes one file per node with its leaf and proof to dir, plus an index.json with the tree's header
+func (g *treeGenerator) writeSplitProofs(rewardsFile rprewards.IRewardsFile, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", dir, err)
+	}
+
+	count := 0
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+
+		// Nodes without rewards aren't in the tree
+		leaf := merkleLeaf(address, info)
+		if leaf == nil {
+			continue
+		}
+		proof, err := info.GetMerkleProof()
+		if err != nil {
+			return fmt.Errorf("error getting Merkle proof for node %s: %w", address.Hex(), err)
+		}
+
+		bytes, err := g.serializeJson(nodeProofFile{
+			Address:          address,
+			RewardNetwork:    info.GetRewardNetwork(),
+			CollateralRpl:    info.GetCollateralRpl(),
+			OracleDaoRpl:     info.GetOracleDaoRpl(),
+			SmoothingPoolEth: info.GetSmoothingPoolEth(),
+			Leaf:             "0x" + hex.EncodeToString(leaf),
+			MerkleProof:      proof,
+		})
+		if err != nil {
+			return fmt.Errorf("error serializing proof for node %s: %w", address.Hex(), err)
+		}
+		path := filepath.Join(dir, address.Hex()+".json")
+		if err := writeFileAtomic(path, bytes, 0644); err != nil {
+			return fmt.Errorf("error saving proof for node %s to %s: %w", address.Hex(), path, err)
+		}
+		count++
+	}
+
+	bytes, err := g.serializeJson(rewardsFile.GetHeader())
+	if err != nil {
+		return fmt.Errorf("error serializing proof index: %w", err)
+	}
+	indexPath := filepath.Join(dir, "index.json")
+	if err := writeFileAtomic(indexPath, bytes, 0644); err != nil {
+		return fmt.Errorf("error saving proof index to %s: %w", indexPath, err)
+	}
+	g.audit.addOutput(dir)
+	g.log.Printlnf("Saved %d node proof files and the index to %s", count, dir)
+
+	return nil
+}
+
+// Serializes a value to JSON, honoring --pretty-print
+func (g *treeGenerator) serializeJson(value interface{}) ([]byte, error) {
+	if g.prettyPrint {
+		return json.MarshalIndent(value, "", "\t")
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Prints the shape of the generated tree and the spread of node rewards, as a sanity check before archiving it
+func (g *treeGenerator) printTreeStats(rewardsFile rprewards.IRewardsFile) error {
+	header := rewardsFile.GetHeader()
+
+	// Only nodes with a leaf are part of the tree
+	var firstLeaf []byte
+	networks := map[uint64]bool{}
+	rplAmounts := []*big.Int{}
+	ethAmounts := []*big.Int{}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		leaf := merkleLeaf(address, info)
+		if leaf == nil {
+			continue
+		}
+		if firstLeaf == nil {
+			firstLeaf = leaf
+		}
+		networks[info.GetRewardNetwork()] = true
+		rplAmounts = append(rplAmounts, big.NewInt(0).Add(&info.GetCollateralRpl().Int, &info.GetOracleDaoRpl().Int))
+		ethAmounts = append(ethAmounts, &info.GetSmoothingPoolEth().Int)
+	}
+
+	g.log.Println()
+	g.log.Println("=== Tree Stats ===")
+	g.log.Printlnf("Merkle root:       %s", common.BytesToHash(header.MerkleTree.Root()).Hex())
+	g.log.Printlnf("Nodes in file:     %d", len(rewardsFile.GetNodeAddresses()))
+	g.log.Printlnf("Leaves:            %d", len(rplAmounts))
+	if firstLeaf == nil {
+		return nil
+	}
+
+	// Every leaf's proof has one hash per level of the tree
+	proof, err := header.MerkleTree.GenerateProof(firstLeaf, 0)
+	if err != nil {
+		return fmt.Errorf("error generating proof to measure the tree depth: %w", err)
+	}
+	g.log.Printlnf("Depth:             %d", len(proof.Hashes))
+	g.log.Printlnf("Reward networks:   %d", len(networks))
+	g.log.Printlnf("RPL per node:      %s", rewardSpread(rplAmounts))
+	g.log.Printlnf("ETH per node:      %s", rewardSpread(ethAmounts))
+	return nil
+}
+
+// Gets the min / median / max of a set of wei amounts, formatted in ETH
+func rewardSpread(amounts []*big.Int) string {
+	sort.Slice(amounts, func(i, j int) bool {
+		return amounts[i].Cmp(amounts[j]) < 0
+	})
+	median := amounts[len(amounts)/2]
+	return fmt.Sprintf("min %.6f, median %.6f, max %.6f", eth.WeiToEth(amounts[0]), eth.WeiToEth(median), eth.WeiToEth(amounts[len(amounts)-1]))
+}
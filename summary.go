@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+)
+
+// Prints the end-of-run report for --summary-only as a single block on stdout, since the regular log is suppressed
+func (g *treeGenerator) printSummary(record *auditRecord) {
+	fmt.Println("=== Summary ===")
+	fmt.Printf("Run ID:          %s\n", record.RunId)
+	if record.Interval != nil {
+		fmt.Printf("Interval:        %d\n", *record.Interval)
+	}
+	if record.MerkleRoot != "" {
+		fmt.Printf("Ruleset:         v%d\n", record.Ruleset)
+		fmt.Printf("Merkle root:     %s\n", record.MerkleRoot)
+	}
+	if record.CanonicalMatch != nil {
+		fmt.Printf("Canonical match: %t\n", *record.CanonicalMatch)
+	}
+	if record.totalSmoothingPoolEth != nil {
+		fmt.Printf("Smoothing Pool ETH distributed: %s wei (%.6f ETH)\n", record.totalSmoothingPoolEth.String(), eth.WeiToEth(&record.totalSmoothingPoolEth.Int))
+	}
+	if record.poolStakerSmoothingPoolEth != nil {
+		fmt.Printf("rETH stakers's share:           %s wei (%.6f ETH)\n", record.poolStakerSmoothingPoolEth.String(), eth.WeiToEth(&record.poolStakerSmoothingPoolEth.Int))
+	}
+	if record.rewardSplit != nil {
+		for _, line := range record.rewardSplit.lines() {
+			fmt.Println(line)
+		}
+	}
+	if g.timer != nil {
+		for _, phase := range g.timer.phases {
+			fmt.Printf("%-22s %s\n", phase.name+":", phase.duration.Round(time.Millisecond))
+		}
+	}
+	fmt.Printf("Duration:        %s\n", time.Duration(record.DurationSeconds*float64(time.Second)).Round(time.Millisecond))
+	for _, path := range record.OutputPaths {
+		fmt.Printf("Wrote:           %s\n", path)
+	}
+	if len(g.warnings) > 0 {
+		fmt.Printf("Warnings:        %d\n", len(g.warnings))
+		for _, warning := range g.warnings {
+			fmt.Printf("- [%s] %s\n", warning.Code, warning.Message)
+		}
+	}
+	if record.Error != "" {
+		fmt.Printf("Error:           %s\n", record.Error)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/goccy/go-json"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+// One EC and BN pair to generate for, and where to save its files
+type generationTarget struct {
+	Ec        string `json:"ec"`
+	Bn        string `json:"bn"`
+	OutputDir string `json:"outputDir"`
+}
+
+// Flags that a targets file provides per target, so they can't also be passed on the command line
+var targetFlags = []string{"ec-endpoint", "bn-endpoint", "output-dir", "smartnode-config"}
+
+// Runs the regular generation once for each target in the --targets-file, e.g. to archive mainnet and a testnet in one invocation.
+// The network is detected from each target's BN as usual. A failing target is reported and the rest still run.
+func GenerateTargets(c *cli.Context) error {
+	logger := log.NewColorLogger(color.FgHiWhite)
+	errLogger := log.NewColorLogger(color.FgRed)
+	path := c.String("targets-file")
+
+	for _, name := range append(targetFlags, "watch") {
+		if c.IsSet(name) && c.Value(name) != false {
+			return fmt.Errorf("--targets-file cannot be combined with --%s", name)
+		}
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading targets file %s: %w", path, err)
+	}
+	var targets []generationTarget
+	if err := json.Unmarshal(bytes, &targets); err != nil {
+		return fmt.Errorf("error parsing targets file %s: %w", path, err)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("targets file %s has no targets", path)
+	}
+
+	var failed []string
+	for i, target := range targets {
+		name := fmt.Sprintf("target %d (EC %s, BN %s)", i, redactUrl(target.Ec), redactUrl(target.Bn))
+		logger.Printlnf("Generating for %s", name)
+
+		// Each target runs the same flow as a single invocation with these flags
+		for flag, value := range map[string]string{"ec-endpoint": target.Ec, "bn-endpoint": target.Bn, "output-dir": target.OutputDir} {
+			if err := c.Set(flag, value); err != nil {
+				return fmt.Errorf("error setting %s for %s: %w", flag, name, err)
+			}
+		}
+		if err := GenerateTree(c); err != nil {
+			errLogger.Printlnf("Error generating for %s: %s", name, err.Error())
+			failed = append(failed, fmt.Sprint(i))
+			continue
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d targets failed: %s", len(failed), len(targets), strings.Join(failed, ", "))
+	}
+	logger.Printlnf("Generated for all %d targets.", len(targets))
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Checks that the per-node amounts add up to the header's totals, both overall and per reward network.
+// Each node's share can be rounded down by a wei, so sums may fall short of a total by up to one wei per node.
+func checkTotals(rewardsFile rprewards.IRewardsFile) error {
+	header := rewardsFile.GetHeader()
+	if header.TotalRewards == nil {
+		return fmt.Errorf("the rewards file has no totals to check against")
+	}
+
+	collateralRpl := big.NewInt(0)
+	oracleDaoRpl := big.NewInt(0)
+	smoothingPoolEth := big.NewInt(0)
+	networks := map[uint64]*rprewards.NetworkRewardsInfo{}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		collateralRpl.Add(collateralRpl, &info.GetCollateralRpl().Int)
+		oracleDaoRpl.Add(oracleDaoRpl, &info.GetOracleDaoRpl().Int)
+		smoothingPoolEth.Add(smoothingPoolEth, &info.GetSmoothingPoolEth().Int)
+
+		network, exists := networks[info.GetRewardNetwork()]
+		if !exists {
+			network = &rprewards.NetworkRewardsInfo{
+				CollateralRpl:    rprewards.NewQuotedBigInt(0),
+				OracleDaoRpl:     rprewards.NewQuotedBigInt(0),
+				SmoothingPoolEth: rprewards.NewQuotedBigInt(0),
+			}
+			networks[info.GetRewardNetwork()] = network
+		}
+		network.CollateralRpl.Add(&network.CollateralRpl.Int, &info.GetCollateralRpl().Int)
+		network.OracleDaoRpl.Add(&network.OracleDaoRpl.Int, &info.GetOracleDaoRpl().Int)
+		network.SmoothingPoolEth.Add(&network.SmoothingPoolEth.Int, &info.GetSmoothingPoolEth().Int)
+	}
+
+	tolerance := big.NewInt(int64(len(rewardsFile.GetNodeAddresses())))
+	var problems []string
+	compare := func(name string, sum *big.Int, total *rprewards.QuotedBigInt) {
+		diff := big.NewInt(0).Sub(totalAmount(total), sum)
+		if diff.CmpAbs(tolerance) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: nodes sum to %s wei but the total is %s wei", name, sum.String(), totalAmount(total).String()))
+		}
+	}
+	compare("collateral RPL", collateralRpl, header.TotalRewards.TotalCollateralRpl)
+	compare("Oracle DAO RPL", oracleDaoRpl, header.TotalRewards.TotalOracleDaoRpl)
+	compare("node operator Smoothing Pool ETH", smoothingPoolEth, header.TotalRewards.NodeOperatorSmoothingPoolEth)
+
+	indices := make([]uint64, 0, len(networks))
+	for index := range networks {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	for _, index := range indices {
+		sums := networks[index]
+		totals, exists := header.NetworkRewards[index]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("network %d has node rewards but no network totals", index))
+			continue
+		}
+		compare(fmt.Sprintf("network %d collateral RPL", index), &sums.CollateralRpl.Int, totals.CollateralRpl)
+		compare(fmt.Sprintf("network %d Oracle DAO RPL", index), &sums.OracleDaoRpl.Int, totals.OracleDaoRpl)
+		compare(fmt.Sprintf("network %d Smoothing Pool ETH", index), &sums.SmoothingPoolEth.Int, totals.SmoothingPoolEth)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the node rewards don't add up to the totals:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Checks that no node appears twice in the rewards file and that no two nodes share a Merkle leaf.
+// The node rewards are keyed by address, so this guards the address list and the leaves built from it;
+// a repeated leaf would leave one of the nodes unable to prove its claim.
+func checkDuplicateNodes(rewardsFile rprewards.IRewardsFile) error {
+	var problems []string
+	addresses := map[common.Address]bool{}
+	leaves := map[string]common.Address{}
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		if addresses[address] {
+			problems = append(problems, fmt.Sprintf("node %s is listed more than once", address.Hex()))
+			continue
+		}
+		addresses[address] = true
+
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		leaf := merkleLeaf(address, info)
+		if leaf == nil {
+			continue
+		}
+		if other, exists := leaves[string(leaf)]; exists {
+			problems = append(problems, fmt.Sprintf("nodes %s and %s have the same Merkle leaf", other.Hex(), address.Hex()))
+			continue
+		}
+		leaves[string(leaf)] = address
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("the rewards file has duplicate node entries:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+)
+
+// Removes every node entry from the rewards file for which keep returns false.
+// The header, including the Merkle root, is left untouched, so the result is only suitable for inspection.
+func filterNodeRewards(rewardsFile rprewards.IRewardsFile, keep func(address common.Address, info rprewards.INodeRewardsInfo) bool) error {
+	switch file := rewardsFile.(type) {
+	case *rprewards.RewardsFile_v1:
+		for address, info := range file.NodeRewards {
+			if !keep(address, info) {
+				delete(file.NodeRewards, address)
+			}
+		}
+	case *rprewards.RewardsFile_v2:
+		for address, info := range file.NodeRewards {
+			if !keep(address, info) {
+				delete(file.NodeRewards, address)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported rewards file type %T", rewardsFile)
+	}
+
+	return nil
+}
+
+// Replaces every node address in the rewards file with its pseudonym under the given key.
+// The file is serialized sorted by address, so the pseudonyms don't keep the order of the real addresses. That's
+// deliberate: the rewarded node list is public, so pseudonyms sorted like the real addresses could be matched by rank.
+func anonymizeNodeAddresses(rewardsFile rprewards.IRewardsFile, key []byte) error {
+	switch file := rewardsFile.(type) {
+	case *rprewards.RewardsFile_v1:
+		anonymized := make(map[common.Address]*rprewards.NodeRewardsInfo_v1, len(file.NodeRewards))
+		for address, info := range file.NodeRewards {
+			anonymized[anonymizeAddress(key, address)] = info
+		}
+		file.NodeRewards = anonymized
+	case *rprewards.RewardsFile_v2:
+		anonymized := make(map[common.Address]*rprewards.NodeRewardsInfo_v2, len(file.NodeRewards))
+		for address, info := range file.NodeRewards {
+			anonymized[anonymizeAddress(key, address)] = info
+		}
+		file.NodeRewards = anonymized
+	default:
+		return fmt.Errorf("unsupported rewards file type %T", rewardsFile)
+	}
+
+	return nil
+}
+
+// Gets the pseudonym for an address: the first 20 bytes of its HMAC-SHA256 under the key.
+// Node addresses are public, so an unkeyed hash could be reversed by hashing the node list.
+func anonymizeAddress(key []byte, address common.Address) common.Address {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(address.Bytes())
+	return common.BytesToAddress(mac.Sum(nil)[:common.AddressLength])
+}
+
+// Gets the key for --anonymize, from --anonymize-salt or else a random one for this run
+func getAnonymizeKey(salt string) ([]byte, error) {
+	if salt != "" {
+		return []byte(salt), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error generating an anonymization key: %w", err)
+	}
+	return key, nil
+}
+
+// Clears every node's Merkle proof from the rewards file, which makes up much of its size.
+// The amounts and the root are left untouched, but nodes can no longer claim with the result.
+func stripMerkleProofs(rewardsFile rprewards.IRewardsFile) error {
+	switch file := rewardsFile.(type) {
+	case *rprewards.RewardsFile_v1:
+		for _, info := range file.NodeRewards {
+			info.MerkleProof = nil
+		}
+	case *rprewards.RewardsFile_v2:
+		for _, info := range file.NodeRewards {
+			info.MerkleProof = nil
+		}
+	default:
+		return fmt.Errorf("unsupported rewards file type %T", rewardsFile)
+	}
+
+	return nil
+}
+
+// The newest rewards file version treegen knows how to produce
+const latestRewardsFileVersion uint64 = 2
+
+// Converts a rewards file and its minipool performance file to an older schema version.
+// Only downgrades are supported, since newer schemas carry data the older ones lack.
+func convertRewardsFile(rewardsFile rprewards.IRewardsFile, version uint64) (rprewards.IRewardsFile, error) {
+	file, ok := rewardsFile.(*rprewards.RewardsFile_v2)
+	if !ok || version != 1 {
+		return nil, fmt.Errorf("cannot convert a version %d rewards file to version %d", rewardsFile.GetHeader().RewardsFileVersion, version)
+	}
+
+	// The header is shared with v1; the Merkle data doesn't depend on the version
+	header := *file.RewardsFileHeader
+	header.RewardsFileVersion = 1
+	converted := &rprewards.RewardsFile_v1{
+		RewardsFileHeader: &header,
+		NodeRewards:       make(map[common.Address]*rprewards.NodeRewardsInfo_v1, len(file.NodeRewards)),
+	}
+	for address, info := range file.NodeRewards {
+		converted.NodeRewards[address] = &rprewards.NodeRewardsInfo_v1{
+			RewardNetwork:    info.RewardNetwork,
+			CollateralRpl:    info.CollateralRpl,
+			OracleDaoRpl:     info.OracleDaoRpl,
+			SmoothingPoolEth: info.SmoothingPoolEth,
+			MerkleData:       info.MerkleData,
+			MerkleProof:      info.MerkleProof,
+		}
+	}
+
+	perf := file.MinipoolPerformanceFile
+	converted.MinipoolPerformanceFile = rprewards.MinipoolPerformanceFile_v1{
+		Index:               perf.Index,
+		Network:             perf.Network,
+		StartTime:           perf.StartTime,
+		EndTime:             perf.EndTime,
+		ConsensusStartBlock: perf.ConsensusStartBlock,
+		ConsensusEndBlock:   perf.ConsensusEndBlock,
+		ExecutionStartBlock: perf.ExecutionStartBlock,
+		ExecutionEndBlock:   perf.ExecutionEndBlock,
+		MinipoolPerformance: make(map[common.Address]*rprewards.SmoothingPoolMinipoolPerformance_v1, len(perf.MinipoolPerformance)),
+	}
+	for address, minipool := range perf.MinipoolPerformance {
+		entry := &rprewards.SmoothingPoolMinipoolPerformance_v1{
+			Pubkey:                  minipool.Pubkey,
+			SuccessfulAttestations:  minipool.SuccessfulAttestations,
+			MissedAttestations:      minipool.MissedAttestations,
+			MissingAttestationSlots: minipool.MissingAttestationSlots,
+		}
+		if total := minipool.SuccessfulAttestations + minipool.MissedAttestations; total > 0 {
+			entry.ParticipationRate = float64(minipool.SuccessfulAttestations) / float64(total)
+		}
+		if minipool.EthEarned != nil {
+			entry.EthEarned = eth.WeiToEth(&minipool.EthEarned.Int)
+		}
+		converted.MinipoolPerformanceFile.MinipoolPerformance[address] = entry
+	}
+
+	return converted, nil
+}
+
+// Parses a set of node addresses from either a file with one address per line, or a comma-separated list
+func parseNodeAddresses(value string) (map[common.Address]bool, error) {
+	var entries []string
+	if bytes, err := os.ReadFile(value); err == nil {
+		entries = strings.Split(string(bytes), "\n")
+	} else if os.IsNotExist(err) {
+		entries = strings.Split(value, ",")
+	} else {
+		return nil, fmt.Errorf("error reading %s: %w", value, err)
+	}
+
+	addresses := map[common.Address]bool{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !common.IsHexAddress(entry) {
+			return nil, fmt.Errorf("%s is not a valid node address", entry)
+		}
+		addresses[common.HexToAddress(entry)] = true
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no node addresses were provided")
+	}
+
+	return addresses, nil
+}
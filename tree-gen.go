@@ -2,11 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	stdlog "log"
 	"math/big"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/goccy/go-json"
@@ -15,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/fatih/color"
 	"github.com/rocket-pool/rocketpool-go/rewards"
 	"github.com/rocket-pool/rocketpool-go/rocketpool"
@@ -31,6 +42,7 @@ import (
 
 const (
 	MaxConcurrentEth1Requests = 200
+	maxConcurrentBlockQueries = 8
 )
 
 // Details about the snapshot block / timestamp for a treegen target
@@ -89,55 +101,308 @@ type treegenArguments struct {
 type treeGenerator struct {
 	log               *log.ColorLogger
 	errLog            *log.ColorLogger
+	clock             clock
 	rp                *rocketpool.RocketPool
 	cfg               *config.RocketPoolConfig
 	mgr               *state.NetworkStateManager
 	recordMgr         *rprewards.RollingRecordManager
 	bn                beacon.Client
+	bnUrl             string
+	bnApiUrl          string
+	ecUrl             string
 	beaconConfig      beacon.Eth2Config
 	targets           targets
-	outputDir         string
+	outputDirs        []string
 	prettyPrint       bool
 	ruleset           uint64
 	useRollingRecords bool
-}
 
-// Generates a new rewards tree based on the command line flags
-func GenerateTree(c *cli.Context) error {
-	// Configure
-	configureHTTP()
+	// If set, replaces the snapshot block's time as the end of a dry run interval
+	endTimeOverride time.Time
 
-	// Initialization
-	interval := c.Int64("interval")
-	targetEpoch := c.Uint64("target-epoch")
-	logger := log.NewColorLogger(color.FgHiWhite)
-	errLogger := log.NewColorLogger(color.FgRed)
+	// Whether to log per-item detail that is otherwise summarized
+	verbose bool
+
+	// If set, replaces the on-chain interval start time
+	startTimeOverride time.Time
+
+	// If set, replaces the on-chain interval time when deriving the intervals passed
+	intervalTimeOverride time.Duration
+
+	// How many slots at the end of an epoch to scan for its last block, 0 for the whole epoch
+	maxSlotScan uint64
+
+	// If set, rewards events are loaded from and saved to this cache instead of always scanning the EC's logs
+	eventsCache *eventsCache
+
+	// If set, used instead of looking up the targeted interval's rewards event
+	rewardsEventOverride *rewards.RewardsEvent
+
+	// If set, used instead of deriving the interval's first slot from the previous rewards event
+	startSlotOverride *uint64
+
+	// Whether a snapshot block without an execution payload uses the closest earlier one that has one
+	nearestElBlock bool
+
+	// Whether a post-Merge snapshot block without an execution payload falls back to finding the EL block by time
+	beaconFallbackToElTime bool
+
+	// The number of invalid-network nodes tolerated before the run fails; negative for no limit
+	maxInvalidNetworks int
+
+	// The most intervals a snapshot may claim have passed before it's treated as a configuration problem; 0 has no limit
+	maxIntervalsPassed uint64
+
+	// Whether to warn about slashed / exited minipool validators at the snapshot
+	warnValidatorIssues bool
+
+	// If set, the rewards file version to serialize instead of the one the ruleset produces
+	schemaVersion uint64
+
+	// Whether to write the snapshot sidecar next to the rewards files
+	writeSnapshot bool
+
+	// Whether to write only the minipool performance file and skip the rewards tree
+	onlyMinipoolPerformance bool
+
+	// Whether to log the raw values behind a partial interval's intervalsPassed
+	logIntervalsPassed bool
+
+	// Whether the BN's slot timing was overridden, which older rulesets can't honor
+	beaconConfigOverridden bool
+
+	// Whether to log every intermediate value of the interval time math
+	debugTiming bool
+
+	// If set, the directory to write one proof file per node to
+	splitProofsDir string
+
+	// If set, the JSONL file each generation's outcome is appended to
+	auditLog string
+
+	// The audit record for the generation in progress; nil if neither auditing nor the summary is enabled
+	audit *auditRecord
+
+	// Print a single report at the end of each generation instead of the regular log
+	summaryOnly bool
+
+	// The ID that every log line and audit record of this run is tagged with
+	runId string
+
+	// Whether to log the SHA-256 of each file written
+	printHashes bool
+
+	// Whether to also export the minipool performance data as CSV
+	performanceCsv bool
+
+	// Whether to only print the Merkle root instead of serializing and writing the files
+	rootOnly bool
+
+	// Whether to check that the node rewards add up to the header's totals
+	checkTotals bool
+
+	// How many nodes' Merkle proofs to verify against the root, 0 to skip the check
+	verifyProofsSample uint64
+
+	// Whether to print statistics about the generated tree
+	printStats bool
+
+	// Whether to print the interval's reward split by category, and where to save it as JSON
+	printRewardSplit bool
+	rewardSplitFile  string
+
+	// If set, a text report of the interval is written here, listing the top reportTop nodes
+	reportPath string
+	reportTop  int
+
+	// Whether to export each rewarded node's RPL stake at the snapshot
+	rplStakes bool
+
+	// Whether to drop nodes with no RPL or ETH rewards from the serialized rewards tree
+	omitZeroRewards bool
+
+	// Whether to replace node addresses with hashes in the serialized rewards tree
+	anonymize bool
+
+	// The HMAC key for the node address pseudonyms, when anonymizing
+	anonymizeKey []byte
+
+	// Whether to leave the Merkle proofs out of the serialized rewards tree
+	noProofs bool
+
+	// If set, where to save every level of the Merkle tree
+	merkleTreePath string
+
+	// If set, where to save every Merkle leaf as CSV
+	leavesCsvPath string
+
+	// If set, where to save each node's change in rewards since the previous interval, and that interval's file if not the default
+	previousDeltasPath  string
+	previousRewardsFile string
 
-	// URL acquisiton
+	// If set, the CPU profile path covering only the state fetch and tree generation
+	generationProfile string
+
+	// If set, the run fails unless the generated Merkle root matches it
+	expectedRoot *common.Hash
+
+	// Whether to return freed memory to the OS between phases
+	lowMemory bool
+
+	// The slot an interrupted state fetch was asked to resume from. The state manager can't resume, so it's only warned about
+	resumeFromSlot uint64
+
+	// Whether a pre-Merge snapshot EL block far from its beacon slot's time fails the run instead of only warning
+	strict bool
+
+	// Whether a mismatch with the canonical root fails the run instead of only warning
+	failOnMismatch bool
+
+	// If set, the snapshot EL block is loaded by this hash instead of by number
+	elBlockHash *common.Hash
+
+	// Whether to log the serialized file sizes before writing them
+	estimateSizes bool
+
+	// If set, the IPFS HTTP API to add and pin the generated files to
+	ipfsApi string
+
+	// How long each upload to the IPFS HTTP API may take
+	ipfsApiTimeout time.Duration
+
+	// If set, the IPFS gateway to download the canonical rewards file from for a full comparison
+	ipfsGateway string
+
+	// How long the download from the IPFS gateway may take
+	ipfsGatewayTimeout time.Duration
+
+	// Whether the generated rewards file must serialize to exactly the canonical file's bytes
+	assertSmartnodeCompat bool
+
+	// If set, the node to print the claim calldata for
+	claimCalldataNode *common.Address
+
+	// If set, the node to print each minipool's attestation record for
+	attestationDetailNode *common.Address
+
+	// If set, the node to print a summary of its rewards, stake, and minipools for
+	nodeSummaryNode *common.Address
+
+	// Warnings raised during the run, repeated in a summary at the end
+	warnings []runWarning
+
+	// Phase timings for --benchmark; nil if disabled
+	timer *phaseTimer
+
+	// If set, only these nodes are kept in the serialized rewards tree
+	nodeFilter map[common.Address]bool
+
+	// If set, the exact snapshot block to target instead of the last block in the target epoch
+	targetBlock *beacon.BeaconBlock
+}
+
+// Clients and network configuration shared by every treegen mode
+type connections struct {
+	ecUrl        string
+	bnUrl        string
+	bnApiUrl     string
+	rp           *rocketpool.RocketPool
+	cfg          *config.RocketPoolConfig
+	bn           beacon.Client
+	mgr          *state.NetworkStateManager
+	beaconConfig beacon.Eth2Config
+	chainID      uint64
+}
+
+// Connects to the EC and BN from the command line flags and detects which network they're on
+func connect(c *cli.Context, logger *log.ColorLogger, timer *phaseTimer) (*connections, error) {
+	// URL acquisiton; explicit flags take precedence over a Smartnode config
 	ecUrl := c.String("ec-endpoint")
-	if ecUrl == "" {
-		return fmt.Errorf("ec-endpoint must be provided")
-	}
 	bnUrl := c.String("bn-endpoint")
+	var smartnode *smartnodeSettings
+	if path := c.String("smartnode-config"); path != "" {
+		var err error
+		smartnode, err = loadSmartnodeSettings(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading Smartnode config: %w", err)
+		}
+		if !c.IsSet("ec-endpoint") {
+			ecUrl = smartnode.ecUrl
+		}
+		if !c.IsSet("bn-endpoint") {
+			bnUrl = smartnode.bnUrl
+		}
+		logger.Printlnf("Using the EC at %s and the BN at %s.", ecUrl, bnUrl)
+	}
 	if ecUrl == "" {
-		return fmt.Errorf("bn-endpoint must be provided")
+		return nil, fmt.Errorf("ec-endpoint must be provided")
+	}
+	if bnUrl == "" {
+		return nil, fmt.Errorf("bn-endpoint must be provided")
 	}
 
-	// Create the EC and BN clients
-	ec, err := ethclient.Dial(ecUrl)
+	// ethclient picks the transport from the scheme; WebSocket dials don't use the tuned HTTP transport
+	ecScheme, err := ecUrlScheme(ecUrl)
 	if err != nil {
-		return fmt.Errorf("error connecting to the EC: %w", err)
+		return nil, err
 	}
-	bn := client.NewStandardHttpClient(bnUrl)
-	beaconConfig, err := bn.GetEth2Config()
-	if err != nil {
-		return fmt.Errorf("error getting beacon config from the BN at %s - %w", bnUrl, err)
+	if ecScheme == "ws" || ecScheme == "wss" {
+		logger.Printlnf("Using a WebSocket connection to the EC.")
+	} else if ecScheme == "" {
+		logger.Printlnf("Using the EC's IPC socket at %s.", ecUrl)
+	}
+
+	// Create the EC and BN clients
+	start := time.Now()
+	userAgent := c.String("user-agent")
+	ecTimeout := c.Duration("ec-timeout")
+	var ec *ethclient.Client
+	if ecScheme == "http" || ecScheme == "https" {
+		// Dial HTTP directly so the timeout and User-Agent can be set; a zero timeout means none
+		rpcClient, err := rpc.DialHTTPWithClient(ecUrl, &http.Client{Timeout: ecTimeout})
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to the EC: %w", err)
+		}
+		rpcClient.SetHeader("User-Agent", userAgent)
+		ec = ethclient.NewClient(rpcClient)
+	} else {
+		if ecTimeout > 0 {
+			return nil, fmt.Errorf("ec-timeout is only supported for HTTP EC endpoints")
+		}
+		ec, err = ethclient.Dial(ecUrl)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to the EC: %w", err)
+		}
 	}
 
-	// Check which network we're on via the BN
-	depositContract, err := bn.GetEth2DepositContract()
+	// The BN client always uses http.DefaultClient.
+	// With --targets-file this runs once per target, so the transport is rebuilt from the default each time rather than wrapped again.
+	// A BN on a Unix socket is still addressed over HTTP; the socket transport ignores the placeholder host.
+	http.DefaultClient.Timeout = c.Duration("bn-timeout")
+	var transport http.RoundTripper = http.DefaultTransport
+	bnApiUrl := bnUrl
+	if socket := bnSocketPath(bnUrl); socket != "" {
+		transport = newUnixSocketTransport(socket)
+		bnApiUrl = "http://localhost"
+		logger.Printlnf("Using the BN's Unix socket at %s.", socket)
+	}
+	if maxBnRequests := c.Int("parallel-state-fetch"); maxBnRequests > 0 {
+		transport = newLimitedTransport(transport, maxBnRequests)
+	}
+	http.DefaultClient.Transport = &userAgentTransport{RoundTripper: transport, userAgent: userAgent}
+	bn := client.NewStandardHttpClient(bnApiUrl)
+	timer.record("Client dial", start)
+	start = time.Now()
+
+	// Check which network we're on via the BN.
+	// This is the first BN call, so retry it in case the BN is only a moment away from being ready.
+	var depositContract beacon.Eth2DepositContract
+	err = retryCall(logger, "getting the deposit contract from the BN", func() error {
+		depositContract, err = bn.GetEth2DepositContract()
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("error getting deposit contract from the BN: %w", err)
+		return nil, fmt.Errorf("error getting deposit contract from the BN at %s after %d attempts: %w", redactUrl(bnUrl), chainCallAttempts, err)
 	}
 	var network cfgtypes.Network
 	switch depositContract.ChainID {
@@ -148,7 +413,31 @@ func GenerateTree(c *cli.Context) error {
 		network = cfgtypes.Network_Prater
 		logger.Printlnf("Beacon node is configured for Prater.")
 	default:
-		return fmt.Errorf("your Beacon node is configured for an unknown network with Chain ID [%d]", depositContract.ChainID)
+		return nil, fmt.Errorf("your Beacon node is configured for an unknown network with Chain ID [%d]", depositContract.ChainID)
+	}
+	if smartnode != nil && smartnode.network != network {
+		return nil, fmt.Errorf("your Smartnode config is for %s, but your Beacon node is configured for %s", smartnode.network, network)
+	}
+
+	// The network comes from the BN, so make sure the EC is on the same chain before doing anything expensive
+	ecChainID, err := ec.ChainID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error getting the EC's chain ID: %w", err)
+	}
+	if ecChainID.Uint64() != depositContract.ChainID {
+		return nil, fmt.Errorf("EC and BN are on different networks: the EC reports chain ID %d, but the BN is configured for chain ID %d (%s)", ecChainID.Uint64(), depositContract.ChainID, network)
+	}
+
+	// A syncing EC can fail historical state calls or answer them with wrong data
+	syncProgress, err := ec.SyncProgress(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error checking whether the EC is synced: %w", err)
+	}
+	if syncProgress != nil {
+		if !c.Bool("allow-syncing") {
+			return nil, fmt.Errorf("EC is not fully synced (at block %d of %d); generation requires a synced archive node", syncProgress.CurrentBlock, syncProgress.HighestBlock)
+		}
+		logger.Printlnf("WARNING: the EC is still syncing (at block %d of %d); continuing because of allow-syncing.", syncProgress.CurrentBlock, syncProgress.HighestBlock)
 	}
 
 	// Create a new config on the proper network
@@ -156,31 +445,501 @@ func GenerateTree(c *cli.Context) error {
 	cfg.Smartnode.Network.Value = network
 
 	// Create the RP wrapper
-	storageContract := cfg.Smartnode.GetStorageAddress()
-	rp, err := rocketpool.NewRocketPool(ec, common.HexToAddress(storageContract))
+	storageContract := common.HexToAddress(cfg.Smartnode.GetStorageAddress())
+	if path := c.String("deployment"); path != "" {
+		storageContract, err = loadDeploymentStorageAddress(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading deployment: %w", err)
+		}
+		logger.Printlnf("Using RocketStorage at %s from %s.", storageContract.Hex(), path)
+	}
+	maxRequests := c.Int("max-concurrent-requests")
+	if maxRequests < 1 {
+		return nil, fmt.Errorf("max-concurrent-requests must be at least 1")
+	}
+	rp, err := rocketpool.NewRocketPool(newLimitedClient(ec, maxRequests), storageContract)
 	if err != nil {
-		return fmt.Errorf("error creating Rocket Pool wrapper: %w", err)
+		return nil, fmt.Errorf("error creating Rocket Pool wrapper: %w", err)
 	}
 
-	// Create the NetworkStateManager
-	mgr, err := state.NewNetworkStateManager(rp, cfg, rp.Client, bn, &logger)
+	// Create the NetworkStateManager. It fetches the beacon config on creation, so reuse its copy
+	// rather than querying the BN a second time.
+	var mgr *state.NetworkStateManager
+	err = retryCall(logger, "getting the beacon config from the BN", func() error {
+		mgr, err = state.NewNetworkStateManager(rp, cfg, rp.Client, bn, logger)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting beacon config from the BN at %s after %d attempts - %w", redactUrl(bnUrl), chainCallAttempts, err)
+	}
+	timer.record("Config fetch", start)
+
+	// Replace the BN's slot timing for custom devnets; the state manager reads its own copy, so update that too
+	if err := overrideBeaconConfig(c, &mgr.BeaconConfig, logger); err != nil {
+		return nil, err
+	}
+
+	return &connections{
+		ecUrl:        ecUrl,
+		bnUrl:        bnUrl,
+		bnApiUrl:     bnApiUrl,
+		rp:           rp,
+		cfg:          cfg,
+		bn:           bn,
+		mgr:          mgr,
+		beaconConfig: mgr.BeaconConfig,
+		chainID:      depositContract.ChainID,
+	}, nil
+}
+
+// The flags that replace the BN's reported slot timing
+var beaconConfigOverrideFlags = []string{"slots-per-epoch", "seconds-per-slot", "genesis-time"}
+
+// Applies the --slots-per-epoch, --seconds-per-slot, and --genesis-time overrides to the beacon config
+func overrideBeaconConfig(c *cli.Context, beaconConfig *beacon.Eth2Config, logger *log.ColorLogger) error {
+	if c.IsSet("slots-per-epoch") || c.IsSet("seconds-per-slot") {
+		if !c.Bool("allow-experimental-networks") {
+			return fmt.Errorf("slots-per-epoch and seconds-per-slot require allow-experimental-networks")
+		}
+		if c.IsSet("slots-per-epoch") {
+			beaconConfig.SlotsPerEpoch = c.Uint64("slots-per-epoch")
+		}
+		if c.IsSet("seconds-per-slot") {
+			beaconConfig.SecondsPerSlot = c.Uint64("seconds-per-slot")
+		}
+		if beaconConfig.SlotsPerEpoch == 0 || beaconConfig.SecondsPerSlot == 0 {
+			return fmt.Errorf("slots-per-epoch and seconds-per-slot must be greater than 0")
+		}
+		logger.Printlnf("WARNING: overriding the BN's slot timing with %d slots per epoch and %d seconds per slot.", beaconConfig.SlotsPerEpoch, beaconConfig.SecondsPerSlot)
+	}
+	if c.IsSet("genesis-time") {
+		if !c.Bool("allow-experimental-networks") {
+			return fmt.Errorf("genesis-time requires allow-experimental-networks")
+		}
+		logger.Printlnf("WARNING: overriding the BN's genesis time of %d with %d.", beaconConfig.GenesisTime, c.Uint64("genesis-time"))
+		beaconConfig.GenesisTime = c.Uint64("genesis-time")
+	}
+	return nil
+}
+
+// Rulesets before v5 re-read the beacon config from the BN instead of the state manager, so they would ignore the overrides
+func checkBeaconConfigOverrideRuleset(ruleset uint64) error {
+	if ruleset < 5 {
+		return fmt.Errorf("--%s only apply to rulesets v5 and later, but ruleset v%d reads the timing from the BN directly", strings.Join(beaconConfigOverrideFlags, ", --"), ruleset)
+	}
+	return nil
+}
+
+// Generates a new rewards tree based on the command line flags
+func GenerateTree(c *cli.Context) error {
+	// Configure
+	configureHTTP()
+
+	// Initialization
+	interval := c.Int64("interval")
+	targetEpoch := c.Uint64("target-epoch")
+	logger := log.NewColorLogger(color.FgHiWhite)
+	errLogger := log.NewColorLogger(color.FgRed)
+
+	if c.IsSet("gc-percent") {
+		if c.Bool("low-memory") {
+			return fmt.Errorf("gc-percent cannot be combined with low-memory, which sets its own GC target")
+		}
+		gcPercent := c.Int("gc-percent")
+		setGCPercent(gcPercent)
+		defer logPeakMemory(&logger, gcPercent)
+	}
+	if c.Bool("low-memory") {
+		enableLowMemory()
+	}
+
+	// Silence the step-by-step log, including the Smartnode's, in favor of the final summary
+	if c.Bool("summary-only") {
+		stdlog.SetOutput(io.Discard)
+		defer stdlog.SetOutput(os.Stderr)
+	}
+
+	// Time each phase if requested
+	var timer *phaseTimer
+	if c.Bool("benchmark") || c.IsSet("metrics-file") {
+		timer = &phaseTimer{}
+	}
+	if c.Bool("benchmark") {
+		defer timer.print(&logger)
+	}
+	wallClock, err := newClock(c.String("now"))
+	if err != nil {
+		return fmt.Errorf("error parsing now: %w", err)
+	}
+	if path := c.String("metrics-file"); path != "" {
+		start := time.Now()
+		startedAt := wallClock.Now()
+		defer func() {
+			if err := writeMetrics(path, timer, start, startedAt); err != nil {
+				errLogger.Printlnf("error writing metrics to %s: %s", path, err.Error())
+			}
+		}()
+	}
+
+	// Catch nonsensical flag combinations before connecting
+	if err := validateFlags(c); err != nil {
+		return err
+	}
+
+	// Connect to the EC and BN
+	conn, err := connect(c, &logger, timer)
 	if err != nil {
 		return err
 	}
+	beaconConfig := conn.beaconConfig
+
+	if c.Bool("print-config") {
+		if err := printEffectiveConfig(c, conn); err != nil {
+			return err
+		}
+	}
+
+	// Make sure every output directory can be written to before doing any expensive work
+	outputDirs := strings.Split(c.String("output-dir"), ",")
+	if c.Bool("output-dir-per-network") {
+		network := string(conn.cfg.Smartnode.Network.Value.(cfgtypes.Network))
+		for i, outputDir := range outputDirs {
+			outputDirs[i], err = networkOutputDir(outputDir, network)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if dataDir := c.String("smartnode-layout"); dataDir != "" {
+		dir, err := smartnodeRewardsDir(dataDir)
+		if err != nil {
+			return err
+		}
+		if c.IsSet("output-dir") {
+			outputDirs = append(outputDirs, dir)
+		} else {
+			outputDirs = []string{dir}
+		}
+	}
+	for _, outputDir := range outputDirs {
+		if err := checkDirWritable(outputDir); err != nil {
+			return fmt.Errorf("output directory [%s] is not writable: %w", outputDir, err)
+		}
+	}
+
+	// Parse the start time override
+	var startTimeOverride time.Time
+	if c.IsSet("start-time") {
+		startTimeOverride, err = parseTime(c.String("start-time"))
+		if err != nil {
+			return fmt.Errorf("error parsing start-time: %w", err)
+		}
+	}
+
+	// Parse the node filter
+	var nodeFilter map[common.Address]bool
+	if c.IsSet("node-filter") {
+		nodeFilter, err = parseNodeAddresses(c.String("node-filter"))
+		if err != nil {
+			return fmt.Errorf("error parsing node-filter: %w", err)
+		}
+	}
+
+	// Parse the end time override
+	var endTimeOverride time.Time
+	if c.IsSet("end-time") {
+		if interval >= 0 || targetEpoch > 0 {
+			return fmt.Errorf("end-time can only be used for a dry run of the current interval without target-epoch")
+		}
+		endTimeOverride, err = parseTime(c.String("end-time"))
+		if err != nil {
+			return fmt.Errorf("error parsing end-time: %w", err)
+		}
+	}
+
+	var anonymizeKey []byte
+	if c.Bool("anonymize") {
+		anonymizeKey, err = getAnonymizeKey(c.String("anonymize-salt"))
+		if err != nil {
+			return err
+		}
+	}
+
+	var nodeSummaryNode *common.Address
+	if c.IsSet("node-summary") {
+		if !common.IsHexAddress(c.String("node-summary")) {
+			return fmt.Errorf("node-summary %s is not a valid node address", c.String("node-summary"))
+		}
+		node := common.HexToAddress(c.String("node-summary"))
+		nodeSummaryNode = &node
+	}
+	var attestationDetailNode *common.Address
+	if c.IsSet("attestation-detail") {
+		if !common.IsHexAddress(c.String("attestation-detail")) {
+			return fmt.Errorf("attestation-detail %s is not a valid node address", c.String("attestation-detail"))
+		}
+		node := common.HexToAddress(c.String("attestation-detail"))
+		attestationDetailNode = &node
+	}
+	var claimCalldataNode *common.Address
+	if c.IsSet("claim-calldata") {
+		if !common.IsHexAddress(c.String("claim-calldata")) {
+			return fmt.Errorf("claim-calldata %s is not a valid node address", c.String("claim-calldata"))
+		}
+		node := common.HexToAddress(c.String("claim-calldata"))
+		claimCalldataNode = &node
+	}
+
+	var expectedRoot *common.Hash
+	if c.IsSet("expected-root") {
+		root, err := parseHash(c.String("expected-root"))
+		if err != nil {
+			return fmt.Errorf("error parsing expected-root: %w", err)
+		}
+		expectedRoot = &root
+	}
+	var elBlockHash *common.Hash
+	if c.IsSet("el-block-hash") {
+		hash, err := parseHash(c.String("el-block-hash"))
+		if err != nil {
+			return fmt.Errorf("error parsing el-block-hash: %w", err)
+		}
+		elBlockHash = &hash
+	}
+
+	schemaVersion := c.Uint64("schema-version")
+	if schemaVersion > latestRewardsFileVersion {
+		return fmt.Errorf("unsupported schema-version %d; supported versions are 1 through %d", schemaVersion, latestRewardsFileVersion)
+	}
 
 	// Create the generator
 	generator := treeGenerator{
-		log:               &logger,
-		errLog:            &errLogger,
-		rp:                rp,
-		cfg:               cfg,
-		bn:                bn,
-		mgr:               mgr,
-		beaconConfig:      beaconConfig,
-		outputDir:         c.String("output-dir"),
-		prettyPrint:       c.Bool("pretty-print"),
-		ruleset:           c.Uint64("ruleset"),
-		useRollingRecords: c.Bool("use-rolling-records"),
+		log:                     &logger,
+		clock:                   wallClock,
+		errLog:                  &errLogger,
+		rp:                      conn.rp,
+		cfg:                     conn.cfg,
+		bn:                      conn.bn,
+		bnUrl:                   conn.bnUrl,
+		bnApiUrl:                conn.bnApiUrl,
+		ecUrl:                   conn.ecUrl,
+		mgr:                     conn.mgr,
+		beaconConfig:            beaconConfig,
+		outputDirs:              outputDirs,
+		prettyPrint:             c.Bool("pretty-print"),
+		ruleset:                 c.Uint64("ruleset"),
+		useRollingRecords:       c.Bool("use-rolling-records"),
+		endTimeOverride:         endTimeOverride,
+		startTimeOverride:       startTimeOverride,
+		intervalTimeOverride:    c.Duration("interval-time"),
+		maxSlotScan:             c.Uint64("max-slot-scan"),
+		claimCalldataNode:       claimCalldataNode,
+		attestationDetailNode:   attestationDetailNode,
+		nodeSummaryNode:         nodeSummaryNode,
+		warnValidatorIssues:     c.Bool("warn-validator-issues"),
+		verbose:                 c.Bool("verbose"),
+		nodeFilter:              nodeFilter,
+		timer:                   timer,
+		estimateSizes:           c.Bool("estimate-sizes"),
+		schemaVersion:           schemaVersion,
+		writeSnapshot:           c.Bool("snapshot-info"),
+		onlyMinipoolPerformance: c.Bool("only-minipool-performance"),
+		logIntervalsPassed:      c.Bool("log-intervals-passed"),
+		beaconConfigOverridden:  c.IsSet("slots-per-epoch") || c.IsSet("seconds-per-slot") || c.IsSet("genesis-time"),
+		debugTiming:             c.Bool("debug-timing"),
+		expectedRoot:            expectedRoot,
+		elBlockHash:             elBlockHash,
+		failOnMismatch:          c.Bool("fail-on-mismatch"),
+		lowMemory:               c.Bool("low-memory"),
+		strict:                  c.Bool("strict"),
+		resumeFromSlot:          c.Uint64("resume-from-slot"),
+		anonymize:               c.Bool("anonymize"),
+		anonymizeKey:            anonymizeKey,
+		noProofs:                c.Bool("no-proofs"),
+		merkleTreePath:          c.String("dump-merkle-tree"),
+		leavesCsvPath:           c.String("dump-leaves-csv"),
+		previousDeltasPath:      c.String("previous-deltas"),
+		previousRewardsFile:     c.String("previous-rewards-file"),
+		omitZeroRewards:         c.Bool("omit-zero-rewards"),
+		printStats:              c.Bool("print-tree-stats"),
+		printRewardSplit:        c.Bool("reward-split"),
+		rewardSplitFile:         c.String("reward-split-file"),
+		checkTotals:             c.Bool("check-totals"),
+		verifyProofsSample:      c.Uint64("verify-proofs-sample"),
+		maxInvalidNetworks:      c.Int("max-invalid-networks"),
+		maxIntervalsPassed:      c.Uint64("max-intervals-passed"),
+		nearestElBlock:          c.Bool("nearest-el-block"),
+		beaconFallbackToElTime:  c.Bool("beacon-fallback-to-el-time"),
+		rplStakes:               c.Bool("rpl-stakes"),
+		reportPath:              c.String("report"),
+		reportTop:               c.Int("report-top"),
+		rootOnly:                c.Bool("root-only"),
+		performanceCsv:          c.Bool("performance-csv"),
+		printHashes:             c.Bool("print-hashes"),
+		assertSmartnodeCompat:   c.Bool("assert-smartnode-compat"),
+		auditLog:                c.String("audit-log"),
+		splitProofsDir:          c.String("split-proofs"),
+		summaryOnly:             c.Bool("summary-only"),
+		runId:                   c.String("run-id"),
+	}
+
+	if c.Bool("pin") {
+		generator.ipfsApi = c.String("ipfs-api")
+		generator.ipfsApiTimeout = c.Duration("ipfs-api-timeout")
+	}
+	if c.Bool("compare-ipfs") || c.Bool("assert-smartnode-compat") {
+		generator.ipfsGateway = c.String("ipfs-gateway")
+		generator.ipfsGatewayTimeout = c.Duration("ipfs-gateway-timeout")
+	}
+	if path := c.String("events-cache"); path != "" {
+		generator.eventsCache, err = loadEventsCache(path, c.Bool("refresh-events-cache"))
+		if err != nil {
+			return err
+		}
+	}
+	if c.Bool("profile-generation-only") {
+		generator.generationProfile = c.String("cpuprofile")
+	}
+	defer generator.printWarnings()
+	if path := c.String("warnings-file"); path != "" {
+		defer func() {
+			if err := generator.writeWarnings(path); err != nil {
+				errLogger.Printlnf("error writing warnings: %s", err.Error())
+			}
+		}()
+	}
+	if path := c.String("block-map"); path != "" {
+		blocks := generator.recordBlockMap()
+		defer func() {
+			if err := generator.writeBlockMap(blocks, path); err != nil {
+				errLogger.Printlnf("error writing block map: %s", err.Error())
+			}
+		}()
+	}
+	if !startTimeOverride.IsZero() {
+		generator.warn(warningStartTimeOverride, "the interval start time is overridden to %s. This is a debugging aid; the resulting tree will not match the canonical one unless the override is exactly what the chain used.", startTimeOverride)
+	}
+	if generator.intervalTimeOverride > 0 {
+		generator.warn(warningIntervalTimeOverride, "the interval time is overridden to %s. This is a debugging aid; the intervals passed, and so the tree, will not match the canonical one unless the override is exactly what the chain used.", generator.intervalTimeOverride)
+	}
+
+	// Resolve any flags that target an exact block rather than an epoch
+	if !endTimeOverride.IsZero() {
+		generator.targetBlock, err = generator.lastBlockBeforeTime(endTimeOverride)
+		if err != nil {
+			return fmt.Errorf("error finding the last block before %s: %w", endTimeOverride, err)
+		}
+		if generator.targetBlock == nil {
+			return fmt.Errorf("unable to find any valid blocks in the epoch preceding %s", endTimeOverride)
+		}
+	}
+	if c.IsSet("target-slot") {
+		if targetEpoch > 0 || !endTimeOverride.IsZero() || c.IsSet("target-el-block") {
+			return fmt.Errorf("target-slot cannot be combined with target-epoch, target-el-block, or end-time")
+		}
+		slot := c.Uint64("target-slot")
+		block, exists, err := generator.bn.GetBeaconBlock(fmt.Sprint(slot))
+		if err != nil {
+			return fmt.Errorf("error getting beacon block for slot %d: %w", slot, err)
+		}
+		if !exists {
+			return fmt.Errorf("slot %d does not have a proposed block; pick a slot with a block or use target-epoch", slot)
+		}
+		generator.targetBlock = &block
+	}
+	if c.IsSet("target-el-block") {
+		if targetEpoch > 0 || !endTimeOverride.IsZero() {
+			return fmt.Errorf("target-el-block cannot be combined with target-epoch or end-time")
+		}
+		generator.targetBlock, err = generator.beaconBlockForElBlock(c.Uint64("target-el-block"))
+		if err != nil {
+			return err
+		}
+	}
+	if c.IsSet("target-date") {
+		if targetEpoch > 0 || !endTimeOverride.IsZero() || c.IsSet("target-slot") || c.IsSet("target-el-block") {
+			return fmt.Errorf("target-date cannot be combined with target-epoch, target-slot, target-el-block, or end-time")
+		}
+		date, err := time.Parse("2006-01-02", c.String("target-date"))
+		if err != nil {
+			return fmt.Errorf("error parsing target-date: %s is not a YYYY-MM-DD date", c.String("target-date"))
+		}
+		endOfDay := date.Add(24*time.Hour - time.Second)
+		generator.targetBlock, err = generator.lastBlockBeforeTime(endOfDay)
+		if err != nil {
+			return fmt.Errorf("error finding the last block before %s: %w", endOfDay, err)
+		}
+		if generator.targetBlock == nil {
+			return fmt.Errorf("unable to find any valid blocks in the epoch preceding %s", endOfDay)
+		}
+	}
+	if c.IsSet("checkpoints-back") {
+		if targetEpoch > 0 || !endTimeOverride.IsZero() || c.IsSet("target-slot") || c.IsSet("target-el-block") || c.IsSet("target-date") {
+			return fmt.Errorf("checkpoints-back cannot be combined with target-epoch, target-slot, target-el-block, target-date, or end-time")
+		}
+		generator.targetBlock, err = generator.checkpointBlock(c.Uint64("checkpoints-back"))
+		if err != nil {
+			return err
+		}
+	}
+	if generator.targetBlock != nil {
+		targetEpoch = generator.targetBlock.Slot / beaconConfig.SlotsPerEpoch
+	}
+
+	// Describe the interval from the command line if its rewards event can't be looked up
+	manualEvent := c.IsSet("interval-start-slot")
+	for _, name := range manualEventFlags {
+		manualEvent = manualEvent || c.IsSet(name)
+	}
+	if c.Bool("hypothetical-window") {
+		if interval >= 0 || targetEpoch > 0 || c.IsSet("consensus-block") || c.IsSet("execution-block") || c.IsSet("interval-start-slot") {
+			return fmt.Errorf("hypothetical-window resolves the interval and its blocks from interval-start and interval-end, so it cannot be combined with an interval, target flags, consensus-block, execution-block, or interval-start-slot")
+		}
+		event, startSlot, err := generator.hypotheticalRewardsEvent(c)
+		if err != nil {
+			return err
+		}
+		generator.rewardsEventOverride = event
+		generator.startSlotOverride = &startSlot
+		interval = int64(event.Index.Uint64())
+	} else if manualEvent {
+		if interval < 0 {
+			return fmt.Errorf("skipping the rewards event lookup requires an interval (-i)")
+		}
+		generator.rewardsEventOverride, err = generator.manualRewardsEvent(c, uint64(interval))
+		if err != nil {
+			return err
+		}
+		if c.IsSet("interval-start-slot") {
+			startSlot := c.Uint64("interval-start-slot")
+			generator.startSlotOverride = &startSlot
+		}
+	}
+
+	// Catch intervals that can't be generated before looking up their events
+	if interval >= 0 && generator.rewardsEventOverride == nil {
+		if err := generator.checkOldestSupportedInterval(uint64(interval), c.Int64("oldest-supported-interval")); err != nil {
+			return err
+		}
+	}
+
+	// Generate each new interval as it's submitted if requested
+	if c.Bool("watch") {
+		if interval >= 0 || targetEpoch > 0 {
+			return fmt.Errorf("watch cannot be combined with an interval or target flags")
+		}
+		return generator.watch(c.Duration("watch-interval"))
+	}
+
+	// Serve trees on demand if running as the serve subcommand
+	if c.Command.Name == "serve" {
+		if interval >= 0 || targetEpoch > 0 || c.Bool("watch") {
+			return fmt.Errorf("serve generates the requested intervals, so it cannot be combined with an interval, target flags, or watch")
+		}
+		if generator.rootOnly || generator.onlyMinipoolPerformance {
+			return fmt.Errorf("serve needs the rewards tree to be saved, so it cannot be combined with root-only or only-minipool-performance")
+		}
+		return generator.serve(c.String("listen"), c.Int("max-pending-requests"))
 	}
 
 	// initialize the generator targets
@@ -198,39 +957,103 @@ func GenerateTree(c *cli.Context) error {
 		return generator.printNetworkInfo()
 	}
 
+	// Generate the interval under two rulesets and report the differences if requested
+	if c.IsSet("simulate-ruleset-upgrade") {
+		if interval >= 0 {
+			return fmt.Errorf("simulate-ruleset-upgrade forecasts the current interval, so it cannot be combined with an interval (-i)")
+		}
+		return generator.simulateRulesetUpgrade(c.Uint64("simulate-ruleset-upgrade"))
+	}
+	if c.IsSet("compare-rulesets") {
+		rulesets, err := parseRulesetPair(c.String("compare-rulesets"))
+		if err != nil {
+			return fmt.Errorf("error parsing compare-rulesets: %w", err)
+		}
+		return generator.compareRulesets(rulesets)
+	}
+
+	// Count the nodes the tree should include and exit if requested
+	if c.Bool("print-eligible-nodes") {
+		return generator.printEligibleNodes()
+	}
+
+	// Export the node operators at the snapshot and exit if requested
+	if path := c.String("roster"); path != "" {
+		return generator.writeRoster(path)
+	}
+
 	return generator.generateTree()
 }
 
 func (g *treeGenerator) getTreegenArgs() (*treegenArguments, error) {
 
 	// Cache the network state at the time of the targeted epoch for later use
+	if g.resumeFromSlot != 0 {
+		g.warn(warningResumeIgnored, "--resume-from-slot %d has no effect: the state manager fetches the network state in a single pass and can't pick up a partial fetch, so the state at slot %d will be fetched from the start", g.resumeFromSlot, g.targets.block.Slot)
+	}
+	start := time.Now()
 	state, err := g.mgr.GetStateForSlot(g.targets.block.Slot)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get state at slot %d: %w", g.targets.block.Slot, err)
+		return nil, g.stateError(err, g.targets.block.Slot)
 	}
+	g.timer.record("State fetch", start)
+	g.releaseMemory()
+
+	return g.getIntervalArgs(state)
+}
 
+// Gets the interval's arguments for the tree generator around the given network state.
+// The state is only stored, so it may be nil when nothing will be generated.
+func (g *treeGenerator) getIntervalArgs(state *state.NetworkState) (*treegenArguments, error) {
 	// If we have a rewardsEvent, we're generating a full interval
 	if g.targets.rewardsEvent != nil {
 		index := g.targets.rewardsEvent.Index.Uint64()
 		startSlot := uint64(0)
-		if index > 0 {
+		if g.startSlotOverride != nil {
+			startSlot = *g.startSlotOverride
+		} else if index > 0 {
 			// Get the start slot for this interval
-			previousRewardsEvent, err := rprewards.GetRewardSnapshotEvent(g.rp, g.cfg, uint64(index-1), nil)
+			previousRewardsEvent, err := g.getRewardsEvent(uint64(index - 1))
 			if err != nil {
-				return nil, fmt.Errorf("error getting event for interval %d: %w", index-1, err)
+				return nil, g.ecError(err, 0, fmt.Sprintf("getting the rewards event for interval %d", index-1))
 			}
 			startSlot, err = getStartSlotForInterval(previousRewardsEvent, g.bn, g.beaconConfig)
 			if err != nil {
-				return nil, fmt.Errorf("error getting start slot for interval %d: %w", index, err)
+				return nil, g.bnError(err, previousRewardsEvent.ConsensusBlock.Uint64(), fmt.Sprintf("getting the start slot for interval %d", index))
 			}
 		}
 
-		elBlockHeader, err := g.rp.Client.HeaderByNumber(context.Background(), g.targets.rewardsEvent.ExecutionBlock)
+		elBlockHeader, err := g.getSnapshotElHeader(g.targets.rewardsEvent.ExecutionBlock)
+		if err != nil {
+			return nil, g.ecError(err, g.targets.rewardsEvent.ExecutionBlock.Uint64(), "getting the snapshot EL block header")
+		}
+
+		// Make sure the EC and BN agree on the snapshot block
+		consensusSlot := g.targets.rewardsEvent.ConsensusBlock.Uint64()
+		consensusBlock, exists, err := g.bn.GetBeaconBlock(fmt.Sprint(consensusSlot))
 		if err != nil {
-			return nil, fmt.Errorf("error getting el block header %d: %w", g.targets.rewardsEvent.ExecutionBlock.Uint64(), err)
+			return nil, g.bnError(err, consensusSlot, "getting the snapshot beacon block")
 		}
+		// Missed slots and pre-Merge blocks have no payload to compare against
+		if exists && consensusBlock.ExecutionBlockNumber != 0 {
+			if consensusBlock.ExecutionBlockNumber != elBlockHeader.Number.Uint64() {
+				return nil, fmt.Errorf("the snapshot beacon block at slot %d has EL block %d but the rewards event points at EL block %d; the EC and BN disagree on the snapshot", consensusSlot, consensusBlock.ExecutionBlockNumber, elBlockHeader.Number.Uint64())
+			}
+			if err := g.checkElHeaderMatchesSlot(elBlockHeader, consensusSlot); err != nil {
+				return nil, err
+			}
+		}
+		startTime := g.targets.rewardsEvent.IntervalStartTime
+		if !g.startTimeOverride.IsZero() {
+			startTime = g.startTimeOverride
+		}
+		consensusTime := g.slotToTime(consensusSlot)
+		g.logTiming("genesisTime=%s (%d) secondsPerSlot=%d slotsPerEpoch=%d", time.Unix(int64(g.beaconConfig.GenesisTime), 0).UTC(), g.beaconConfig.GenesisTime, g.beaconConfig.SecondsPerSlot, g.beaconConfig.SlotsPerEpoch)
+		g.logTiming("event: consensusSlot=%d blockTime=%s (%d) elBlockTime=%s (%d)", consensusSlot, consensusTime.UTC(), consensusTime.Unix(), time.Unix(int64(elBlockHeader.Time), 0).UTC(), elBlockHeader.Time)
+		g.logTiming("event: startTime=%s (%d) startTimeOverridden=%t endTime=%s (%d) intervalsPassed=%d", startTime.UTC(), startTime.Unix(), !g.startTimeOverride.IsZero(), g.targets.rewardsEvent.IntervalEndTime.UTC(), g.targets.rewardsEvent.IntervalEndTime.Unix(), g.targets.rewardsEvent.IntervalsPassed.Uint64())
+
 		return &treegenArguments{
-			startTime:       g.targets.rewardsEvent.IntervalStartTime,
+			startTime:       startTime,
 			endTime:         g.targets.rewardsEvent.IntervalEndTime,
 			index:           index,
 			intervalsPassed: g.targets.rewardsEvent.IntervalsPassed.Uint64(),
@@ -266,26 +1089,141 @@ func (g *treeGenerator) lastBlockInEpoch(epoch uint64) (*beacon.BeaconBlock, err
 
 	// Get the last block proposed in the targeted epoch.
 	// If the targeted epoch has no proposals, return nil, nil
-	end := epoch * g.beaconConfig.SlotsPerEpoch
-	start := end + g.beaconConfig.SlotsPerEpoch - 1
-	for slot := start; slot >= end; slot-- {
-		block, exists, err := g.bn.GetBeaconBlock(fmt.Sprint(slot))
+	// All of the epoch's slots are queried concurrently so missed proposals at the end don't add round trips.
+	// With --max-slot-scan, only that many slots at the end of the epoch are queried.
+	slots := g.beaconConfig.SlotsPerEpoch
+	capped := g.maxSlotScan > 0 && g.maxSlotScan < slots
+	if capped {
+		slots = g.maxSlotScan
+	}
+	start := (epoch+1)*g.beaconConfig.SlotsPerEpoch - slots
+	blocks := make([]beacon.BeaconBlock, slots)
+	exists := make([]bool, slots)
+	errs := make([]error, slots)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentBlockQueries)
+	for i := range blocks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			blocks[i], exists[i], errs[i] = g.bn.GetBeaconBlock(fmt.Sprint(start + uint64(i)))
+		}(i)
+	}
+	wg.Wait()
+
+	// Pick the highest proposed slot; errors on slots below it don't matter
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if exists[i] {
+			return &blocks[i], nil
+		}
+		g.logMissingSlot(start + uint64(i))
+	}
+
+	// Only a full scan can tell that the epoch had no proposals at all
+	if capped {
+		return nil, fmt.Errorf("no proposed block in the last %d slots of epoch %d; raise --max-slot-scan to scan more of the epoch", slots, epoch)
+	}
+	return nil, nil
+}
+
+// Gets the last block proposed at or before the given time.
+// If none of the slots in the preceding epoch were proposed, return nil, nil
+func (g *treeGenerator) lastBlockBeforeTime(t time.Time) (*beacon.BeaconBlock, error) {
+	genesisTime := time.Unix(int64(g.beaconConfig.GenesisTime), 0)
+	if t.Before(genesisTime) {
+		return nil, fmt.Errorf("time %s is before the beacon chain genesis (%s)", t, genesisTime)
+	}
+	slot := uint64(t.Sub(genesisTime) / (time.Duration(g.beaconConfig.SecondsPerSlot) * time.Second))
+
+	for i := uint64(0); i < g.beaconConfig.SlotsPerEpoch && i <= slot; i++ {
+		block, exists, err := g.bn.GetBeaconBlock(fmt.Sprint(slot - i))
 		if err != nil {
 			return nil, err
 		}
 
 		if exists {
-			// We found it, so set it and exit
 			return &block, nil
 		}
+		g.logMissingSlot(slot - i)
+	}
+
+	return nil, nil
+}
+
+// Logs a slot that was skipped while resolving a block because it had no proposal.
+// Networks with many missed proposals would flood the output, so this is only shown with --verbose.
+func (g *treeGenerator) logMissingSlot(slot uint64) {
+	if g.verbose {
+		g.log.Printlnf("Slot %d was missing, checking the previous slot", slot)
+	}
+}
+
+// Walks back from the given slot to the closest beacon block with an execution payload, looking back at most one epoch
+func (g *treeGenerator) nearestBlockWithPayload(slot uint64) (*beacon.BeaconBlock, error) {
+	block, err := g.findBlockWithPayload(slot)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("no beacon block with an execution payload in the epoch before slot %d; is it pre-Merge?", slot)
 	}
+	return block, nil
+}
 
+// Gets the closest beacon block at or up to an epoch before the given slot with an execution payload.
+// If there isn't one, return nil, nil
+func (g *treeGenerator) findBlockWithPayload(slot uint64) (*beacon.BeaconBlock, error) {
+	for i := uint64(0); i < g.beaconConfig.SlotsPerEpoch && i <= slot; i++ {
+		block, exists, err := g.bn.GetBeaconBlock(fmt.Sprint(slot - i))
+		if err != nil {
+			return nil, g.bnError(err, slot-i, "getting the beacon block")
+		}
+		if exists && block.ExecutionBlockNumber != 0 {
+			return &block, nil
+		}
+	}
 	return nil, nil
 }
 
+// Gets the beacon block whose execution payload is the given EL block
+func (g *treeGenerator) beaconBlockForElBlock(elBlock uint64) (*beacon.BeaconBlock, error) {
+	header, err := g.getElHeader(big.NewInt(0).SetUint64(elBlock))
+	if err != nil {
+		return nil, fmt.Errorf("error getting EL block %d: %w", elBlock, err)
+	}
+
+	// Post-merge, every EL block is the payload of the beacon block proposed at the same time
+	genesisTime := g.beaconConfig.GenesisTime
+	if header.Time < genesisTime {
+		return nil, fmt.Errorf("EL block %d precedes the beacon chain genesis", elBlock)
+	}
+	slot := (header.Time - genesisTime) / g.beaconConfig.SecondsPerSlot
+
+	block, exists, err := g.bn.GetBeaconBlock(fmt.Sprint(slot))
+	if err != nil {
+		return nil, fmt.Errorf("error getting beacon block for slot %d: %w", slot, err)
+	}
+	if !exists || block.ExecutionBlockNumber != elBlock {
+		return nil, fmt.Errorf("EL block %d has no corresponding beacon block (expected it in slot %d); is it a pre-merge block?", elBlock, slot)
+	}
+
+	return &block, nil
+}
+
 func (g *treeGenerator) setTargets(interval int64, targetEpoch uint64) error {
 	var err error
 
+	// Finality is only as trustworthy as the BN's view of the chain
+	if err := g.checkBnOptimistic(); err != nil {
+		return err
+	}
+
 	// Validate that the target epoch is finalized
 	if targetEpoch > 0 {
 		beaconHead, err := g.bn.GetBeaconHead()
@@ -294,14 +1232,17 @@ func (g *treeGenerator) setTargets(interval int64, targetEpoch uint64) error {
 		}
 
 		if targetEpoch > beaconHead.FinalizedEpoch {
-			return fmt.Errorf("targeted epoch has not yet been finalized")
+			return fmt.Errorf("target epoch %d not finalized; the latest finalized epoch is %d", targetEpoch, beaconHead.FinalizedEpoch)
 		}
 	}
 
 	// If interval isn't set, we're generating a preview of the current interval
 	if interval < 0 {
 		var block *beacon.BeaconBlock
-		if targetEpoch == 0 {
+		if g.targetBlock != nil {
+			// An exact snapshot block was resolved from the flags, so use it directly
+			block = g.targetBlock
+		} else if targetEpoch == 0 {
 			// No targetEpoch was passed, so set it to the latest finalized epoch
 			b, err := g.mgr.GetLatestFinalizedBeaconBlock()
 			if err != nil {
@@ -340,9 +1281,14 @@ func (g *treeGenerator) setTargets(interval int64, targetEpoch uint64) error {
 
 	// We're generating a previous interval (full or partial)
 	// Get the corresponding rewards event for that interval
-	rewardsEvent, err := rprewards.GetRewardSnapshotEvent(g.rp, g.cfg, uint64(interval), nil)
-	if err != nil {
-		return err
+	var rewardsEvent rewards.RewardsEvent
+	if g.rewardsEventOverride != nil {
+		rewardsEvent = *g.rewardsEventOverride
+	} else {
+		rewardsEvent, err = g.getRewardsEvent(uint64(interval))
+		if err != nil {
+			return err
+		}
 	}
 
 	// If targetEpoch isn't set, we're generating a full interval
@@ -359,46 +1305,152 @@ func (g *treeGenerator) setTargets(interval int64, targetEpoch uint64) error {
 			return fmt.Errorf("unable to find any valid blocks in epoch %d. Was your BN checkpoint synced against a slot that occurred after this epoch?", targetEpoch)
 		}
 
-		return nil
+		return nil
+	}
+
+	// We're generating a partial interval
+	// Ensure the target slot happens *before* the end of the interval
+	eventBlock := rewardsEvent.ConsensusBlock.Uint64()
+	finalEpochOfInterval := eventBlock / g.beaconConfig.SlotsPerEpoch
+	if targetEpoch == finalEpochOfInterval {
+		return fmt.Errorf("target epoch %d was the end of the targeted interval %d.\nRerun without -t", targetEpoch, interval)
+	}
+	if targetEpoch > finalEpochOfInterval {
+		return fmt.Errorf("target epoch %d was after targeted interval %d", targetEpoch, interval)
+	}
+
+	// Ensure the target epoch started *after* the start of the interval, which should land on the start of an epoch boundary
+	epochStartTime := g.slotToTime(targetEpoch * g.beaconConfig.SlotsPerEpoch)
+	if epochStartTime.Before(rewardsEvent.IntervalStartTime) {
+		return fmt.Errorf("target epoch %d was before targeted interval %d", targetEpoch, interval)
+	}
+
+	// Cache the target block for later use
+	if g.targetBlock != nil {
+		g.targets.block = g.targetBlock
+	} else {
+		block, found, err := g.bn.GetBeaconBlock(fmt.Sprint(eventBlock))
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("Unable to find the ending block for interval %d (slot %d). Was your BN checkpoint synced against a slot that occurred after this epoch?", interval, eventBlock)
+		}
+		g.targets.block = &block
+	}
+
+	g.targets.snapshotDetails, err = g.getSnapshotDetails()
+	if err != nil {
+		return err
+	}
+
+	// Inform the user of the range they're querying
+	g.log.Printlnf("Targeting a portion of a previous interval (%d)", g.targets.snapshotDetails.index)
+	g.targets.snapshotDetails.log(g.log)
+
+	return nil
+}
+
+// The part of the EC client used to look up headers, so tests can fake it
+type headerSource interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Gets an EL block header, treating a missing header as an error rather than returning nil
+func (g *treeGenerator) getElHeader(number *big.Int) (*types.Header, error) {
+	return getElHeaderFrom(g.rp.Client, number)
+}
+
+// Gets an EL block header from the given source, treating a missing header as an error
+func getElHeaderFrom(source headerSource, number *big.Int) (*types.Header, error) {
+	header, err := source.HeaderByNumber(context.Background(), number)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("EL block header for block %s not available; is the EC synced past it?", number.String())
 	}
+	return header, nil
+}
 
-	// We're generating a partial interval
-	// Ensure the target slot happens *before* the end of the interval
-	eventBlock := rewardsEvent.ConsensusBlock.Uint64()
-	finalEpochOfInterval := eventBlock / g.beaconConfig.SlotsPerEpoch
-	if targetEpoch == finalEpochOfInterval {
-		return fmt.Errorf("target epoch %d was the end of the targeted interval %d.\nRerun without -t", targetEpoch, interval)
+// Gets the header of the snapshot EL block, which the CL snapshot block says is the given number.
+// If --el-block-hash is set, the header is loaded by that hash instead so a reorg can't silently swap it, and it must have the expected number.
+// A momentarily lagging EC is given time to import the block first.
+func (g *treeGenerator) getSnapshotElHeader(number *big.Int) (*types.Header, error) {
+	if err := g.waitForElBlock(number); err != nil {
+		return nil, err
 	}
-	if targetEpoch > finalEpochOfInterval {
-		return fmt.Errorf("target epoch %d was after targeted interval %d", targetEpoch, interval)
+	if g.elBlockHash == nil {
+		return g.getElHeader(number)
 	}
 
-	// Ensure the target epoch started *after* the start of the interval, which should land on the start of an epoch boundary
-	epochStartTime := g.slotToTime(targetEpoch * g.beaconConfig.SlotsPerEpoch)
-	if epochStartTime.Before(rewardsEvent.IntervalStartTime) {
-		return fmt.Errorf("target epoch %d was before targeted interval %d", targetEpoch, interval)
+	header, err := g.rp.Client.HeaderByHash(context.Background(), *g.elBlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("error getting EL block %s; is it on the EC's canonical chain?: %w", g.elBlockHash.Hex(), err)
 	}
+	if header.Number.Cmp(number) != 0 {
+		return nil, fmt.Errorf("EL block %s is block %s, but the CL snapshot block's payload is block %s; the EC may be on a different fork than the one the snapshot was taken on", g.elBlockHash.Hex(), header.Number.String(), number.String())
+	}
+	g.log.Printlnf("Using EL block %s (%s) pinned by hash.", header.Number.String(), g.elBlockHash.Hex())
+	return header, nil
+}
 
-	// Cache the target block for later use
-	block, found, err := g.bn.GetBeaconBlock(fmt.Sprint(eventBlock))
-	if err != nil {
-		return err
+// Checks that an EL block header is the payload of the beacon block at the given slot by comparing their times.
+// A mismatch means the EC and BN disagree about the canonical chain, usually because of a reorg.
+func (g *treeGenerator) checkElHeaderMatchesSlot(header *types.Header, slot uint64) error {
+	elTime := time.Unix(int64(header.Time), 0)
+	slotTime := g.slotToTime(slot)
+	if !elTime.Equal(slotTime) {
+		return fmt.Errorf("EL block %d has a timestamp of %s, but beacon slot %d is at %s; the EC and BN may disagree about the canonical chain (was there a reorg?)", header.Number.Uint64(), elTime, slot, slotTime)
+	}
+	return nil
+}
+
+// Checks that an EL block found by time rather than by payload, as before the Merge, is close to the beacon slot's time.
+// It can't be after the slot, and PoW block times vary, so up to an epoch before it is allowed; anything further
+// means the time lookup resolved the wrong block. This warns unless --strict is set.
+func (g *treeGenerator) checkElTimeNearSlot(header *types.Header, slot uint64) error {
+	elTime := time.Unix(int64(header.Time), 0)
+	slotTime := g.slotToTime(slot)
+	tolerance := time.Duration(g.beaconConfig.SlotsPerEpoch*g.beaconConfig.SecondsPerSlot) * time.Second
+	if !elTime.After(slotTime) && slotTime.Sub(elTime) <= tolerance {
+		return nil
 	}
-	if !found {
-		return fmt.Errorf("Unable to find the ending block for interval %d (slot %d). Was your BN checkpoint synced against a slot that occurred after this epoch?", interval, eventBlock)
+	message := fmt.Sprintf("EL block %d has a timestamp of %s, but beacon slot %d is at %s; the EL block found for the snapshot time may be the wrong one", header.Number.Uint64(), elTime, slot, slotTime)
+	if g.strict {
+		return fmt.Errorf("%s", message)
 	}
-	g.targets.block = &block
+	g.warn(warningElTimeMismatch, "%s", message)
+	return nil
+}
 
-	g.targets.snapshotDetails, err = g.getSnapshotDetails()
-	if err != nil {
-		return err
+// Logs one line of the time math behind an interval's boundaries if --debug-timing is set
+func (g *treeGenerator) logTiming(format string, v ...interface{}) {
+	if g.debugTiming {
+		g.log.Printlnf("Timing: "+format, v...)
 	}
+}
 
-	// Inform the user of the range they're querying
-	g.log.Printlnf("Targeting a portion of a previous interval (%d)", g.targets.snapshotDetails.index)
-	g.targets.snapshotDetails.log(g.log)
+// Parses a time provided as either an RFC3339 timestamp or unix seconds
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
 
-	return nil
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is neither an RFC3339 timestamp nor unix seconds", value)
+	}
+	return t, nil
+}
+
+// Parses a 32-byte hex hash, with or without the 0x prefix
+func parseHash(value string) (common.Hash, error) {
+	bytes, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil || len(bytes) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("%s is not a 32-byte hex hash", value)
+	}
+	return common.BytesToHash(bytes), nil
 }
 
 // Gets the timestamp for a Beacon slot
@@ -421,6 +1473,19 @@ func (g *treeGenerator) generateRewardsFile(treegen *rprewards.TreeGenerator) (r
 func (g *treeGenerator) serializeMinipoolPerformance(rewardsFile rprewards.IRewardsFile) ([]byte, error) {
 	perfFile := rewardsFile.GetMinipoolPerformanceFile()
 
+	// The encoder sorts the minipool map by address, so the only order left to pin down is each minipool's
+	// missing attestation slots; the CID must not change between runs over the same interval
+	for _, address := range perfFile.GetMinipoolAddresses() {
+		performance, exists := perfFile.GetSmoothingPoolPerformance(address)
+		if !exists {
+			continue
+		}
+		slots := performance.GetMissingAttestationSlots()
+		sort.Slice(slots, func(i, j int) bool {
+			return slots[i] < slots[j]
+		})
+	}
+
 	if g.prettyPrint {
 		return perfFile.SerializeHuman()
 	}
@@ -428,7 +1493,9 @@ func (g *treeGenerator) serializeMinipoolPerformance(rewardsFile rprewards.IRewa
 	return perfFile.Serialize()
 }
 
-// Serializes the rewards tree file in to JSON
+// Serializes the rewards tree file in to JSON.
+// The encoder sorts map keys in both forms, so archived pretty-printed files only differ where their contents do;
+// don't pass json.UnorderedMap here.
 func (g *treeGenerator) serializeRewardsTree(rewardsFile rprewards.IRewardsFile) ([]byte, error) {
 	if g.prettyPrint {
 		return json.MarshalIndent(rewardsFile, "", "\t")
@@ -437,49 +1504,113 @@ func (g *treeGenerator) serializeRewardsTree(rewardsFile rprewards.IRewardsFile)
 	return json.Marshal(rewardsFile)
 }
 
-// Writes both the performance file and the rewards file to disk
+// Gets the paths of the rewards tree and minipool performance files for an interval in the given directory
+func (g *treeGenerator) outputPaths(outputDir string, index uint64) (string, string) {
+	network := string(g.cfg.Smartnode.Network.Value.(cfgtypes.Network))
+	rewardsTreePath := filepath.Join(outputDir, fmt.Sprintf(config.RewardsTreeFilenameFormat, network, index))
+	minipoolPerformancePath := filepath.Join(outputDir, fmt.Sprintf(config.MinipoolPerformanceFilenameFormat, network, index))
+	return rewardsTreePath, minipoolPerformancePath
+}
+
+// Writes both the performance file and the rewards file to each output directory
 func (g *treeGenerator) writeFiles(rewardsFile rprewards.IRewardsFile) error {
 	g.log.Printlnf("Saving JSON files...")
 	index := rewardsFile.GetHeader().Index
 
-	// Get the output paths
-	rewardsTreePath := filepath.Join(g.outputDir, fmt.Sprintf(config.RewardsTreeFilenameFormat, string(g.cfg.Smartnode.Network.Value.(cfgtypes.Network)), index))
-	minipoolPerformancePath := filepath.Join(g.outputDir, fmt.Sprintf(config.MinipoolPerformanceFilenameFormat, string(g.cfg.Smartnode.Network.Value.(cfgtypes.Network)), index))
-
 	// Serialize the minipool performance file
+	start := time.Now()
 	minipoolPerformanceBytes, err := g.serializeMinipoolPerformance(rewardsFile)
 	if err != nil {
 		return fmt.Errorf("error serializing minipool performance file into JSON: %w", err)
 	}
 
-	// Write it to disk
-	err = os.WriteFile(minipoolPerformancePath, minipoolPerformanceBytes, 0644)
-	if err != nil {
-		return fmt.Errorf("error saving minipool performance file to %s: %w", minipoolPerformancePath, err)
+	// Serialize the rewards tree to JSON, unless only the performance file was requested
+	var wrapperBytes []byte
+	if !g.onlyMinipoolPerformance {
+		rewardsFile.SetMinipoolPerformanceFileCID("---")
+		wrapperBytes, err = g.serializeRewardsTree(rewardsFile)
+		if err != nil {
+			return fmt.Errorf("error serializing proof wrapper into JSON: %w", err)
+		}
 	}
+	g.timer.record("Serialization", start)
+	g.log.Printlnf("Generation complete! Saving tree...")
 
-	g.log.Printlnf("Saved minipool performance file to %s", minipoolPerformancePath)
-	rewardsFile.SetMinipoolPerformanceFileCID("---")
-
-	// Serialize the rewards tree to JSON
-	wrapperBytes, err := g.serializeRewardsTree(rewardsFile)
-	if err != nil {
-		return fmt.Errorf("error serializing proof wrapper into JSON: %w", err)
+	// Report how much space the files will take before touching the disk
+	if g.estimateSizes {
+		total := (len(minipoolPerformanceBytes) + len(wrapperBytes)) * len(g.outputDirs)
+		g.log.Printlnf("Minipool performance file size: %s", formatSize(len(minipoolPerformanceBytes)))
+		if !g.onlyMinipoolPerformance {
+			g.log.Printlnf("Rewards tree file size:         %s", formatSize(len(wrapperBytes)))
+		}
+		g.log.Printlnf("Total to write:                 %s across %d output director(ies)", formatSize(total), len(g.outputDirs))
 	}
-	g.log.Printlnf("Generation complete! Saving tree...")
 
-	// Write the rewards tree to disk
-	err = os.WriteFile(rewardsTreePath, wrapperBytes, 0644)
-	if err != nil {
-		return fmt.Errorf("error saving rewards tree file to %s: %w", rewardsTreePath, err)
+	// Write them to disk
+	start = time.Now()
+	for _, outputDir := range g.outputDirs {
+		rewardsTreePath, minipoolPerformancePath := g.outputPaths(outputDir, index)
+		err = writeFileAtomic(minipoolPerformancePath, minipoolPerformanceBytes, 0644)
+		if err != nil {
+			return fmt.Errorf("error saving minipool performance file to %s: %w", minipoolPerformancePath, err)
+		}
+		g.log.Printlnf("Saved minipool performance file to %s", minipoolPerformancePath)
+		g.audit.addOutput(minipoolPerformancePath)
+		if g.onlyMinipoolPerformance {
+			continue
+		}
+
+		err = writeFileAtomic(rewardsTreePath, wrapperBytes, 0644)
+		if err != nil {
+			return fmt.Errorf("error saving rewards tree file to %s: %w", rewardsTreePath, err)
+		}
+		g.log.Printlnf("Saved rewards snapshot file to %s", rewardsTreePath)
+		g.audit.addOutput(rewardsTreePath)
 	}
+	g.timer.record("File write", start)
 
-	g.log.Printlnf("Saved rewards snapshot file to %s", rewardsTreePath)
+	// Every output directory gets the same bytes, so each file only needs hashing once
+	if g.printHashes {
+		rewardsTreePath, minipoolPerformancePath := g.outputPaths("", index)
+		g.log.Printlnf("SHA-256 of %s: %x", minipoolPerformancePath, sha256.Sum256(minipoolPerformanceBytes))
+		if !g.onlyMinipoolPerformance {
+			g.log.Printlnf("SHA-256 of %s: %x", rewardsTreePath, sha256.Sum256(wrapperBytes))
+		}
+	}
+	if g.onlyMinipoolPerformance {
+		g.log.Printlnf("Successfully generated the minipool performance file for interval %d; the rewards tree was not written", index)
+		return nil
+	}
 	g.log.Printlnf("Successfully generated rewards snapshot for interval %d", index)
 
 	return nil
 }
 
+// Fails if an expected root was provided and the generated tree doesn't match it
+func (g *treeGenerator) checkExpectedRoot(header *rprewards.RewardsFileHeader) error {
+	if g.expectedRoot == nil {
+		return nil
+	}
+
+	root := common.BytesToHash(header.MerkleTree.Root())
+	if root != *g.expectedRoot {
+		return fmt.Errorf("your Merkle tree had a root of %s, but the expected root was %s", root.Hex(), g.expectedRoot.Hex())
+	}
+	g.log.Printlnf("Your Merkle tree's root matches the expected root of %s.", g.expectedRoot.Hex())
+	return nil
+}
+
+// Returned when --fail-on-mismatch is set and the generated root doesn't match the canonical one
+var errCanonicalMismatch = errors.New("the generated Merkle root does not match the canonical root")
+
+// Fails the run on a canonical root mismatch if requested, so monitoring can alert on divergence alone
+func (g *treeGenerator) checkCanonicalMismatch(mismatch bool) error {
+	if mismatch && g.failOnMismatch {
+		return fmt.Errorf("interval %d: %w", g.targets.rewardsEvent.Index.Uint64(), errCanonicalMismatch)
+	}
+	return nil
+}
+
 // Create the manager for rolling records to use (if applicable) and update the record to the target slot
 func (g *treeGenerator) prepareRecordManager(args *treegenArguments) error {
 	// Ignore this on old rulesets without rolling records
@@ -573,10 +1704,34 @@ func (g *treeGenerator) getGenerator(args *treegenArguments) (*rprewards.TreeGen
 	if err != nil {
 		return nil, fmt.Errorf("error creating tree generator: %w", err)
 	}
+	if g.beaconConfigOverridden {
+		rulesets := []uint64{g.ruleset}
+		if g.ruleset == 0 {
+			rulesets = []uint64{out.GetGeneratorRulesetVersion(), out.GetApproximatorRulesetVersion()}
+		}
+		for _, ruleset := range rulesets {
+			if err := checkBeaconConfigOverrideRuleset(ruleset); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return out, nil
 }
 
+// Gets the Smoothing Pool contract's address with the given RocketStorage lookup.
+// Unregistered contracts have a zero address in RocketStorage, so that is an error.
+func getSmoothingPoolAddress(getAddress func(contractName string, opts *bind.CallOpts) (*common.Address, error), opts *bind.CallOpts) (common.Address, error) {
+	address, err := getAddress("rocketSmoothingPool", opts)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("error getting smoothing pool contract: %w", err)
+	}
+	if *address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("Smoothing Pool contract not found on this network deployment (EL block %d)", opts.BlockNumber.Uint64())
+	}
+	return *address, nil
+}
+
 // Approximates the rETH stakers' share of the Smoothing Pool's current balance
 func (g *treeGenerator) approximateRethSpRewards() error {
 	args, err := g.getTreegenArgs()
@@ -594,11 +1749,11 @@ func (g *treeGenerator) approximateRethSpRewards() error {
 		args.block.Slot, opts.BlockNumber.Uint64(), args.startTime, args.endTime)
 
 	// Get the Smoothing Pool contract's balance
-	smoothingPoolContract, err := g.rp.GetContract("rocketSmoothingPool", opts)
+	smoothingPoolAddress, err := getSmoothingPoolAddress(g.rp.GetAddress, opts)
 	if err != nil {
-		return fmt.Errorf("error getting smoothing pool contract: %w", err)
+		return err
 	}
-	smoothingPoolBalance, err := g.rp.Client.BalanceAt(context.Background(), *smoothingPoolContract.Address, opts.BlockNumber)
+	smoothingPoolBalance, err := g.rp.Client.BalanceAt(context.Background(), smoothingPoolAddress, opts.BlockNumber)
 	if err != nil {
 		return fmt.Errorf("error getting smoothing pool balance: %w", err)
 	}
@@ -626,11 +1781,35 @@ func (g *treeGenerator) approximateRethSpRewards() error {
 }
 
 // Generate a complete rewards tree
-func (g *treeGenerator) generateTree() error {
+func (g *treeGenerator) generateTree() (err error) {
+	// Record the outcome of the run if requested
+	if g.auditLog != "" || g.summaryOnly {
+		g.audit = &auditRecord{RunId: g.runId, Timestamp: g.clock.Now().UTC(), OutputPaths: []string{}}
+		start := time.Now()
+		defer func() {
+			err = g.finishAudit(start, err)
+		}()
+	}
+
+	stopProfile, err := g.startGenerationProfile()
+	if err != nil {
+		return err
+	}
+	defer stopProfile()
+
 	args, err := g.getTreegenArgs()
 	if err != nil {
 		return fmt.Errorf("error compiling treegen arguments: %w", err)
 	}
+	g.audit.setInterval(args.index)
+
+	// Report any minipool validators that may explain unexpected rewards
+	if g.warnValidatorIssues {
+		g.logValidatorIssues(args.state)
+	}
+
+	// Summarize the network's composition at the snapshot
+	g.logMinipoolSummary(args.state)
 
 	// Create the tree generator
 	treegen, err := g.getGenerator(args)
@@ -644,30 +1823,278 @@ func (g *treeGenerator) generateTree() error {
 	if err != nil {
 		return fmt.Errorf("error generating Merkle tree: %w", err)
 	}
+	g.timer.record("Tree generation", start)
+	stopProfile()
+	g.releaseMemory()
 
 	header := rewardsFile.GetHeader()
+	g.audit.setTree(header.RulesetVersion, header.MerkleRoot)
+	g.audit.setTotals(header.TotalRewards)
 	for address, network := range header.InvalidNetworkNodes {
-		g.log.Printlnf("WARNING: Node %s has invalid network %d assigned! Using 0 (mainnet) instead.", address.Hex(), network)
+		g.warn(warningInvalidNetworkNode, "Node %s has invalid network %d assigned! Using 0 (mainnet) instead.", address.Hex(), network)
+	}
+	invalidNetworks := len(header.InvalidNetworkNodes)
+	g.log.Printlnf("%d node(s) had an invalid reward network.", invalidNetworks)
+	if g.maxInvalidNetworks >= 0 && invalidNetworks > g.maxInvalidNetworks {
+		return fmt.Errorf("%d node(s) had an invalid reward network, which is more than the allowed %d", invalidNetworks, g.maxInvalidNetworks)
 	}
 	g.log.Printlnf("Finished in %s", time.Since(start).String())
+	if totals := header.TotalRewards; totals != nil && totals.TotalSmoothingPoolEth != nil && totals.PoolStakerSmoothingPoolEth != nil {
+		g.log.Printlnf("Total Smoothing Pool ETH distributed: %s wei (%.6f ETH)", totals.TotalSmoothingPoolEth.String(), eth.WeiToEth(&totals.TotalSmoothingPoolEth.Int))
+		g.log.Printlnf("rETH stakers's share:                 %s wei (%.6f ETH)", totals.PoolStakerSmoothingPoolEth.String(), eth.WeiToEth(&totals.PoolStakerSmoothingPoolEth.Int))
+	}
+	split := getRewardSplit(header)
+	if g.printRewardSplit {
+		for _, line := range split.lines() {
+			g.log.Println(line)
+		}
+		g.audit.setRewardSplit(split)
+	}
 
-	// Validate the Merkle root
-	if g.targets.rewardsEvent != nil {
+	// Catch arithmetic bugs in the ruleset before anything is written
+	if g.checkTotals {
+		if err := checkTotals(rewardsFile); err != nil {
+			return err
+		}
+		if err := checkDuplicateNodes(rewardsFile); err != nil {
+			if g.strict {
+				return err
+			}
+			g.warn(warningDuplicateNodes, "%s", err.Error())
+		} else {
+			g.log.Printlnf("The node rewards add up to the interval's totals, and every node has its own leaf.")
+		}
+	}
+
+	// Validate the Merkle root; an event built from the command line doesn't carry one
+	canonicalMismatch := false
+	if g.rewardsEventOverride != nil {
+		g.warn(warningRootUnchecked, "the rewards event was provided on the command line, so the canonical Merkle root is unknown and wasn't checked. Use --expected-root to check it.")
+	} else if g.targets.rewardsEvent != nil {
 		root := common.BytesToHash(header.MerkleTree.Root())
+		g.audit.setCanonicalMatch(root == g.targets.rewardsEvent.MerkleRoot)
 		if root != g.targets.rewardsEvent.MerkleRoot {
-			g.log.Printlnf("WARNING: your Merkle tree had a root of %s, but the canonical Merkle tree's root was %s. This file will not be usable for claiming rewards.", root.Hex(), g.targets.rewardsEvent.MerkleRoot.Hex())
+			canonicalMismatch = true
+			g.warn(warningRootMismatch, "your Merkle tree had a root of %s, but the canonical Merkle tree's root was %s. This file will not be usable for claiming rewards.", root.Hex(), g.targets.rewardsEvent.MerkleRoot.Hex())
 		} else {
 			g.log.Printlnf("Your Merkle tree's root of %s matches the canonical root! You will be able to use this file for claiming rewards.", header.MerkleRoot)
 		}
 	}
 
+	if g.ipfsGateway != "" {
+		if err := g.compareWithIpfs(rewardsFile); err != nil {
+			return err
+		}
+	}
+	if g.assertSmartnodeCompat {
+		if err := g.checkSmartnodeCompat(rewardsFile); err != nil {
+			return err
+		}
+	}
+
+	if g.nodeSummaryNode != nil {
+		if err := g.printNodeSummary(rewardsFile, args.state, *g.nodeSummaryNode); err != nil {
+			return err
+		}
+	}
+	if g.attestationDetailNode != nil {
+		if err := g.printAttestationDetail(rewardsFile, args.state, *g.attestationDetailNode); err != nil {
+			return err
+		}
+	}
+	if g.claimCalldataNode != nil {
+		if err := g.printClaimCalldata(rewardsFile, *g.claimCalldataNode); err != nil {
+			return err
+		}
+	}
+
+	if g.verifyProofsSample > 0 {
+		if err := g.checkProofsSample(rewardsFile, g.verifyProofsSample); err != nil {
+			return err
+		}
+	}
+
+	if g.printStats {
+		if err := g.printTreeStats(rewardsFile); err != nil {
+			return err
+		}
+	}
+
+	// Skip the files entirely if only the root was requested
+	if g.rootOnly {
+		g.log.Printlnf("Merkle root for interval %d: %s", header.Index, common.BytesToHash(header.MerkleTree.Root()).Hex())
+		if err := g.checkExpectedRoot(header); err != nil {
+			return err
+		}
+		return g.checkCanonicalMismatch(canonicalMismatch)
+	}
+
+	// Export the full tree, its leaves, the per-node proofs, and the changes since the last interval while the file
+	// still has every node under its real address
+	if g.merkleTreePath != "" {
+		err = g.writeMerkleTree(rewardsFile, g.merkleTreePath)
+		if err != nil {
+			return err
+		}
+	}
+	if g.leavesCsvPath != "" {
+		err = g.writeLeavesCsv(rewardsFile, g.leavesCsvPath)
+		if err != nil {
+			return err
+		}
+	}
+	if g.splitProofsDir != "" {
+		err = g.writeSplitProofs(rewardsFile, g.splitProofsDir)
+		if err != nil {
+			return err
+		}
+	}
+	if g.previousDeltasPath != "" {
+		err = g.writePreviousDeltas(rewardsFile, g.previousDeltasPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Convert to the requested schema
+	if g.schemaVersion != 0 && g.schemaVersion != header.RewardsFileVersion {
+		rewardsFile, err = convertRewardsFile(rewardsFile, g.schemaVersion)
+		if err != nil {
+			return fmt.Errorf("error converting rewards file to schema version %d: %w", g.schemaVersion, err)
+		}
+		g.log.Printlnf("Converted the rewards file from schema version %d to %d.", header.RewardsFileVersion, g.schemaVersion)
+		header = rewardsFile.GetHeader()
+	}
+
+	// Trim the output down to the requested nodes
+	if g.nodeFilter != nil {
+		total := len(rewardsFile.GetNodeAddresses())
+		err = filterNodeRewards(rewardsFile, func(address common.Address, _ rprewards.INodeRewardsInfo) bool {
+			return g.nodeFilter[address]
+		})
+		if err != nil {
+			return fmt.Errorf("error filtering nodes: %w", err)
+		}
+		g.warn(warningNodeFilter, "node filter kept %d of %d nodes. The Merkle root and proofs still refer to the full tree; this file is for inspection only and cannot be used to claim rewards.", len(rewardsFile.GetNodeAddresses()), total)
+	}
+
+	// Drop nodes that earned nothing. They have no Merkle leaf, so the root and proofs are unaffected, but the file
+	// no longer matches the canonical one byte for byte.
+	if g.omitZeroRewards {
+		total := len(rewardsFile.GetNodeAddresses())
+		err = filterNodeRewards(rewardsFile, func(address common.Address, info rprewards.INodeRewardsInfo) bool {
+			return merkleLeaf(address, info) != nil
+		})
+		if err != nil {
+			return fmt.Errorf("error omitting zero-reward nodes: %w", err)
+		}
+		g.warn(warningZeroRewardsOmitted, "omitted %d of %d nodes with no rewards. This file differs from the canonical one and is for analysis only.", total-len(rewardsFile.GetNodeAddresses()), total)
+	}
+
+	// Hide the node addresses for sharing
+	if g.anonymize {
+		err = anonymizeNodeAddresses(rewardsFile, g.anonymizeKey)
+		if err != nil {
+			return fmt.Errorf("error anonymizing node addresses: %w", err)
+		}
+		g.warn(warningAnonymized, "node addresses in the rewards file are replaced with hashes. The Merkle root and proofs still refer to the real addresses; this file is for sharing only and cannot be used to claim rewards.")
+	}
+
+	// Shrink the file for analysis
+	if g.noProofs {
+		err = stripMerkleProofs(rewardsFile)
+		if err != nil {
+			return fmt.Errorf("error removing Merkle proofs: %w", err)
+		}
+		g.warn(warningNoProofs, "the Merkle proofs are left out of the rewards file. This file is NOT claimable and is for analysis only.")
+	}
+
 	err = g.writeFiles(rewardsFile)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Files are still written on a mismatch so they can be inspected
+	err = g.checkExpectedRoot(header)
+	if err != nil {
+		return err
+	}
+
+	if g.performanceCsv {
+		err = g.writePerformanceCsv(rewardsFile, args.state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.rplStakes {
+		err = g.writeRplStakes(rewardsFile, args.state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.reportPath != "" {
+		err = g.writeReport(rewardsFile, g.reportPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.rewardSplitFile != "" {
+		err = g.writeRewardSplit(split, g.rewardSplitFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.writeSnapshot {
+		err = g.writeSnapshotSidecar(args)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Upload the artifacts to IPFS if requested
+	if g.ipfsApi != "" {
+		err = g.pinFiles(header.Index)
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.checkCanonicalMismatch(canonicalMismatch)
+
+}
+
+// Gets how long an interval is supposed to take at the given block, or the --interval-time override
+func (g *treeGenerator) getIntervalTime(opts *bind.CallOpts) (time.Duration, error) {
+	if g.intervalTimeOverride > 0 {
+		return g.intervalTimeOverride, nil
+	}
+	intervalTime, err := rewards.GetClaimIntervalTime(g.rp, opts)
+	if err != nil {
+		return 0, g.ecError(err, opts.BlockNumber.Uint64(), "getting the claim interval time")
+	}
+	return intervalTime, nil
+}
+
+// Calculates the intervals passed the same way the watchtower does: floor division of the time since the start by the
+// interval time. A slot exactly on a boundary counts the interval that just ended, and a slot before the start counts none.
+func intervalsPassed(slotTime time.Time, startTime time.Time, intervalTime time.Duration) uint64 {
+	timeSinceStart := slotTime.Sub(startTime)
+	if timeSinceStart <= 0 {
+		return 0
+	}
+	return uint64(timeSinceStart / intervalTime)
+}
 
+// Gets the end time of a snapshot: the target block's slot time, or the --end-time override
+func (g *treeGenerator) getSnapshotEndTime() time.Time {
+	if !g.endTimeOverride.IsZero() {
+		return g.endTimeOverride
+	}
+	return g.slotToTime(g.targets.block.Slot)
 }
 
 // Create a rewards snapshot at the target block
@@ -675,29 +2102,70 @@ func (g *treeGenerator) getSnapshotDetails() (*snapshotDetails, error) {
 	var err error
 	var opts bind.CallOpts
 
-	endTime := g.slotToTime(g.targets.block.Slot)
+	// The end time is handed to the tree generator as the end of the interval; it bounds the
+	// Smoothing Pool eligibility window and is recorded in the file header. intervalsPassed is
+	// derived separately from the snapshot slot time below.
+	endTime := g.getSnapshotEndTime()
 
 	// Get the number of the EL block matching the CL snapshot block
+	elBlock := g.targets.block
+	if elBlock.ExecutionBlockNumber == 0 && g.nearestElBlock {
+		elBlock, err = g.nearestBlockWithPayload(g.targets.block.Slot)
+		if err != nil {
+			return nil, err
+		}
+		g.log.Printlnf("Slot %d has no execution payload; using EL block %d from slot %d instead.", g.targets.block.Slot, elBlock.ExecutionBlockNumber, elBlock.Slot)
+	}
 	var snapshotElBlockHeader *types.Header
-	if g.targets.block.ExecutionBlockNumber == 0 {
+	if elBlock.ExecutionBlockNumber == 0 {
 		// No EL data so the Merge hasn't happened yet, figure out the EL block based on the Epoch ending time
+		if g.elBlockHash != nil {
+			return nil, fmt.Errorf("el-block-hash requires a post-Merge snapshot block, but slot %d has no execution payload", g.targets.block.Slot)
+		}
+
+		// Post-Merge, a block without a payload shouldn't happen, so only estimate by time if asked to
+		if !g.beaconFallbackToElTime {
+			payloadBlock, err := g.findBlockWithPayload(elBlock.Slot)
+			if err != nil {
+				return nil, err
+			}
+			if payloadBlock != nil {
+				return nil, fmt.Errorf("slot %d has no execution payload, but slot %d does, so the snapshot is after the Merge; use nearest-el-block or beacon-fallback-to-el-time to pick how to resolve its EL block", elBlock.Slot, payloadBlock.Slot)
+			}
+		}
 		snapshotElBlockHeader, err = rprewards.GetELBlockHeaderForTime(endTime, g.rp)
 		if err != nil {
-			return nil, fmt.Errorf("error getting EL block for time %s: %w", endTime, err)
+			return nil, g.ecError(err, 0, fmt.Sprintf("getting the EL block for time %s", endTime))
+		}
+		if snapshotElBlockHeader == nil {
+			return nil, fmt.Errorf("EL block header for time %s not available; is the EC synced past it?", endTime)
+		}
+		if err := g.checkElTimeNearSlot(snapshotElBlockHeader, elBlock.Slot); err != nil {
+			return nil, err
 		}
 		opts.BlockNumber = snapshotElBlockHeader.Number
+		g.log.Printlnf("Slot %d has no execution payload; resolved the snapshot EL block %d by the interval end time %s.", elBlock.Slot, opts.BlockNumber.Uint64(), endTime)
 	} else {
-		opts.BlockNumber = big.NewInt(0).SetUint64(g.targets.block.ExecutionBlockNumber)
-		snapshotElBlockHeader, err = g.rp.Client.HeaderByNumber(context.Background(), opts.BlockNumber)
+		opts.BlockNumber = big.NewInt(0).SetUint64(elBlock.ExecutionBlockNumber)
+		snapshotElBlockHeader, err = g.getSnapshotElHeader(opts.BlockNumber)
 		if err != nil {
-			return nil, fmt.Errorf("error getting EL block %d: %w", opts.BlockNumber.Uint64(), err)
+			return nil, g.ecError(err, opts.BlockNumber.Uint64(), "getting the snapshot EL block header")
+		}
+		if err := g.checkElHeaderMatchesSlot(snapshotElBlockHeader, elBlock.Slot); err != nil {
+			return nil, err
 		}
+		g.log.Printlnf("Resolved the snapshot EL block %d from slot %d's execution payload.", opts.BlockNumber.Uint64(), elBlock.Slot)
 	}
 
 	// Get the interval index
-	indexBig, err := rewards.GetRewardIndex(g.rp, &opts)
+	// This is usually the first contract call, so a failure here most often means the EC itself is the problem
+	var indexBig *big.Int
+	err = g.retry("getting current reward index", func() error {
+		indexBig, err = rewards.GetRewardIndex(g.rp, &opts)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error getting current reward index: %w", err)
+		return nil, g.ecError(err, opts.BlockNumber.Uint64(), fmt.Sprintf("getting the current reward index after %d attempts; check that the EC endpoint is reachable, synced, and has state for that block (past intervals need an archive node)", chainCallAttempts))
 	}
 	index := indexBig.Uint64()
 
@@ -705,30 +2173,44 @@ func (g *treeGenerator) getSnapshotDetails() (*snapshotDetails, error) {
 	startSlot := uint64(0)
 	if index > 0 {
 		// Get the start slot for this interval
-		previousRewardsEvent, err := rprewards.GetRewardSnapshotEvent(g.rp, g.cfg, uint64(index-1), nil)
+		previousRewardsEvent, err := g.getRewardsEvent(uint64(index - 1))
 		if err != nil {
-			return nil, fmt.Errorf("error getting event for interval %d: %w", index-1, err)
+			return nil, g.ecError(err, 0, fmt.Sprintf("getting the rewards event for interval %d", index-1))
 		}
 		startSlot, err = getStartSlotForInterval(previousRewardsEvent, g.bn, g.beaconConfig)
 		if err != nil {
-			return nil, fmt.Errorf("error getting start slot for interval %d: %w", index, err)
+			return nil, g.bnError(err, previousRewardsEvent.ConsensusBlock.Uint64(), fmt.Sprintf("getting the start slot for interval %d", index))
 		}
 	}
 
 	// Get the start time for the interval, and how long an interval is supposed to take
 	startTime, err := rewards.GetClaimIntervalTimeStart(g.rp, &opts)
 	if err != nil {
-		return nil, fmt.Errorf("error getting claim interval start time: %w", err)
+		return nil, g.ecError(err, opts.BlockNumber.Uint64(), "getting the claim interval start time")
 	}
-	intervalTime, err := rewards.GetClaimIntervalTime(g.rp, &opts)
+	if !g.startTimeOverride.IsZero() {
+		startTime = g.startTimeOverride
+	}
+	intervalTime, err := g.getIntervalTime(&opts)
 	if err != nil {
-		return nil, fmt.Errorf("error getting claim interval time: %w", err)
+		return nil, err
 	}
 
-	// Calculate the intervals passed
-	blockTime := time.Unix(int64(snapshotElBlockHeader.Time), 0)
-	timeSinceStart := blockTime.Sub(startTime)
-	intervalsPassed := uint64(timeSinceStart / intervalTime)
+	// Calculate the intervals passed, measured at the snapshot slot
+	slotTime := g.slotToTime(g.targets.block.Slot)
+	timeSinceStart := slotTime.Sub(startTime)
+	passed := intervalsPassed(slotTime, startTime, intervalTime)
+	if g.logIntervalsPassed {
+		g.log.Printlnf("intervalsPassed: timeSinceStart=%s (%d s) intervalTime=%s (%d s) intervalsPassed=%d",
+			timeSinceStart, int64(timeSinceStart.Seconds()), intervalTime, int64(intervalTime.Seconds()), passed)
+	}
+	g.logTiming("genesisTime=%s (%d) secondsPerSlot=%d slotsPerEpoch=%d", time.Unix(int64(g.beaconConfig.GenesisTime), 0).UTC(), g.beaconConfig.GenesisTime, g.beaconConfig.SecondsPerSlot, g.beaconConfig.SlotsPerEpoch)
+	g.logTiming("snapshotSlot=%d blockTime=%s (%d) endTime=%s (%d) endTimeOverridden=%t", g.targets.block.Slot, slotTime.UTC(), slotTime.Unix(), endTime.UTC(), endTime.Unix(), !g.endTimeOverride.IsZero())
+	g.logTiming("startTime=%s (%d) startTimeOverridden=%t intervalTime=%s (%d s) intervalTimeOverridden=%t", startTime.UTC(), startTime.Unix(), !g.startTimeOverride.IsZero(), intervalTime, int64(intervalTime.Seconds()), g.intervalTimeOverride > 0)
+	g.logTiming("timeSinceStart=%s (%d s) intervalsPassed=%d", timeSinceStart, int64(timeSinceStart.Seconds()), passed)
+	if g.maxIntervalsPassed > 0 && passed > g.maxIntervalsPassed {
+		return nil, fmt.Errorf("%d intervals have passed since the interval start of %s (interval time %s), which is more than the allowed %d; this usually means the start time or interval time override or the system clock is wrong", passed, startTime, intervalTime, g.maxIntervalsPassed)
+	}
 
 	return &snapshotDetails{
 		index:                 index,
@@ -737,7 +2219,7 @@ func (g *treeGenerator) getSnapshotDetails() (*snapshotDetails, error) {
 		startSlot:             startSlot,
 		snapshotBeaconBlock:   g.targets.block.Slot,
 		snapshotElBlockHeader: snapshotElBlockHeader,
-		intervalsPassed:       intervalsPassed,
+		intervalsPassed:       passed,
 	}, nil
 }
 
@@ -774,15 +2256,19 @@ func getStartSlotForInterval(previousIntervalEvent rewards.RewardsEvent, bc beac
 
 // Print information about the current network and interval info
 func (g *treeGenerator) printNetworkInfo() error {
-	args, err := g.getTreegenArgs()
+	// None of the printed details depend on the network state, so skip the expensive fetch
+	args, err := g.getIntervalArgs(nil)
 	if err != nil {
 		return fmt.Errorf("error compiling treegen arguments: %w", err)
 	}
 
-	// Generate the rewards file
-	generator, err := g.getGenerator(args)
+	// The ruleset versions only depend on the interval and network, so the generator doesn't need a state or rolling record
+	generator, err := rprewards.NewTreeGenerator(
+		g.log, "", g.rp, g.cfg, g.bn, args.index,
+		args.startTime, args.endTime, args.block.Slot, args.elBlockHeader,
+		args.intervalsPassed, nil, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("error creating tree generator: %w", err)
 	}
 
 	g.log.Println()
@@ -792,7 +2278,7 @@ func (g *treeGenerator) printNetworkInfo() error {
 
 	// Find the event for the previous interval
 	if args.index > 0 {
-		rewardsEvent, err := rprewards.GetRewardSnapshotEvent(g.rp, g.cfg, args.index-1, nil)
+		rewardsEvent, err := g.getRewardsEvent(args.index - 1)
 		if err != nil {
 			return fmt.Errorf("error getting rewards submission event for previous interval (%d): %w", args.index-1, err)
 		}
@@ -810,7 +2296,23 @@ func (g *treeGenerator) printNetworkInfo() error {
 	return nil
 }
 
-// Configure HTTP transport settings
+// Gets the scheme of the EC URL, making sure ethclient can dial it.
+// A path without a scheme is an IPC socket.
+func ecUrlScheme(ecUrl string) (string, error) {
+	parsed, err := url.Parse(ecUrl)
+	if err != nil {
+		return "", fmt.Errorf("error parsing ec-endpoint [%s]: %w", ecUrl, err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "ws", "wss", "":
+		return parsed.Scheme, nil
+	default:
+		return "", fmt.Errorf("ec-endpoint [%s] has unsupported scheme [%s]; use http(s), ws(s), or an IPC path", ecUrl, parsed.Scheme)
+	}
+}
+
+// Configure HTTP transport settings.
+// This is still needed with a WebSocket EC, since the BN client is always HTTP.
 func configureHTTP() {
 
 	// The watchtower daemon makes a large number of concurrent RPC requests to the Eth1 client
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/fatih/color"
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+)
+
+func TestIntervalsPassed(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	intervalTime := 28 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		slotTime time.Time
+		expected uint64
+	}{
+		{"exact multiple", start.Add(2 * intervalTime), 2},
+		{"one second before a multiple", start.Add(2*intervalTime - time.Second), 1},
+		{"one second after a multiple", start.Add(2*intervalTime + time.Second), 2},
+		{"at the start", start, 0},
+		{"before the start", start.Add(-time.Hour), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if passed := intervalsPassed(test.slotTime, start, intervalTime); passed != test.expected {
+				t.Fatalf("expected %d intervals passed, got %d", test.expected, passed)
+			}
+		})
+	}
+}
+
+// Builds a small rewards file whose minipools miss the given slots, in the given order
+func newPerformanceFixture(missingSlots []uint64) *rprewards.RewardsFile_v2 {
+	perf := rprewards.MinipoolPerformanceFile_v2{
+		RewardsFileVersion:  2,
+		Index:               7,
+		Network:             "mainnet",
+		MinipoolPerformance: map[common.Address]*rprewards.SmoothingPoolMinipoolPerformance_v2{},
+	}
+	for i := 1; i <= 3; i++ {
+		slots := make([]uint64, len(missingSlots))
+		copy(slots, missingSlots)
+		perf.MinipoolPerformance[common.BigToAddress(big.NewInt(int64(i)))] = &rprewards.SmoothingPoolMinipoolPerformance_v2{
+			Pubkey:                  fmt.Sprintf("0x%096x", i),
+			SuccessfulAttestations:  100,
+			MissedAttestations:      uint64(len(slots)),
+			AttestationScore:        rprewards.NewQuotedBigInt(int64(1000 * i)),
+			MissingAttestationSlots: slots,
+			EthEarned:               rprewards.NewQuotedBigInt(int64(i)),
+		}
+	}
+	return &rprewards.RewardsFile_v2{
+		RewardsFileHeader:       &rprewards.RewardsFileHeader{RewardsFileVersion: 2, Index: 7},
+		NodeRewards:             map[common.Address]*rprewards.NodeRewardsInfo_v2{},
+		MinipoolPerformanceFile: perf,
+	}
+}
+
+func TestSerializeMinipoolPerformanceIsDeterministic(t *testing.T) {
+	for _, prettyPrint := range []bool{false, true} {
+		g := &treeGenerator{prettyPrint: prettyPrint}
+		first, err := g.serializeMinipoolPerformance(newPerformanceFixture([]uint64{300, 100, 500, 200, 400}))
+		if err != nil {
+			t.Fatalf("error serializing: %s", err.Error())
+		}
+		second, err := g.serializeMinipoolPerformance(newPerformanceFixture([]uint64{500, 400, 300, 200, 100}))
+		if err != nil {
+			t.Fatalf("error serializing: %s", err.Error())
+		}
+		if !bytes.Equal(first, second) {
+			t.Fatalf("serializing the same performance with shuffled missing slots gave different bytes (pretty print %t):\n%s\n%s", prettyPrint, first, second)
+		}
+	}
+}
+
+// Builds a rewards file with several nodes and networks, inserting them in the given order
+func newRewardsFixture(order []int) *rprewards.RewardsFile_v2 {
+	file := &rprewards.RewardsFile_v2{
+		RewardsFileHeader: &rprewards.RewardsFileHeader{
+			RewardsFileVersion: 2,
+			Index:              7,
+			Network:            "mainnet",
+			TotalRewards:       &rprewards.TotalRewards{},
+			NetworkRewards:     map[uint64]*rprewards.NetworkRewardsInfo{},
+		},
+		NodeRewards: map[common.Address]*rprewards.NodeRewardsInfo_v2{},
+	}
+	for _, i := range order {
+		network := uint64(i % 3)
+		file.NodeRewards[common.BigToAddress(big.NewInt(int64(i)))] = &rprewards.NodeRewardsInfo_v2{
+			RewardNetwork:    network,
+			CollateralRpl:    rprewards.NewQuotedBigInt(int64(10 * i)),
+			OracleDaoRpl:     rprewards.NewQuotedBigInt(0),
+			SmoothingPoolEth: rprewards.NewQuotedBigInt(int64(i)),
+			MerkleProof:      []string{fmt.Sprintf("0x%064x", i)},
+		}
+		file.NetworkRewards[network] = &rprewards.NetworkRewardsInfo{
+			CollateralRpl:    rprewards.NewQuotedBigInt(int64(network)),
+			OracleDaoRpl:     rprewards.NewQuotedBigInt(0),
+			SmoothingPoolEth: rprewards.NewQuotedBigInt(int64(network)),
+		}
+	}
+	return file
+}
+
+func TestSerializeRewardsTreeIsDeterministic(t *testing.T) {
+	g := &treeGenerator{prettyPrint: true}
+	expected, err := g.serializeRewardsTree(newRewardsFixture([]int{1, 2, 3, 4, 5, 6, 7, 8}))
+	if err != nil {
+		t.Fatalf("error serializing: %s", err.Error())
+	}
+
+	// Map iteration order is random, so a few runs are needed to catch unsorted keys
+	for run := 0; run < 10; run++ {
+		actual, err := g.serializeRewardsTree(newRewardsFixture([]int{8, 3, 6, 1, 7, 2, 5, 4}))
+		if err != nil {
+			t.Fatalf("error serializing: %s", err.Error())
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Fatalf("pretty printing the same rewards file twice gave different bytes:\n%s\n%s", expected, actual)
+		}
+	}
+}
+
+// An EC that doesn't have any headers yet
+type missingHeaderSource struct{}
+
+func (missingHeaderSource) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return nil, nil
+}
+
+func TestGetElHeaderMissing(t *testing.T) {
+	header, err := getElHeaderFrom(missingHeaderSource{}, big.NewInt(12345))
+	if err == nil {
+		t.Fatalf("expected an error for a missing header, got header %v", header)
+	}
+	if !strings.Contains(err.Error(), "not available; is the EC synced past it?") {
+		t.Fatalf("unexpected error for a missing header: %s", err.Error())
+	}
+}
+
+func TestGetSmoothingPoolAddressNotDeployed(t *testing.T) {
+	getAddress := func(contractName string, opts *bind.CallOpts) (*common.Address, error) {
+		return &common.Address{}, nil
+	}
+	_, err := getSmoothingPoolAddress(getAddress, &bind.CallOpts{BlockNumber: big.NewInt(12345)})
+	if err == nil {
+		t.Fatalf("expected an error for a zero Smoothing Pool address")
+	}
+	if !strings.Contains(err.Error(), "Smoothing Pool contract not found") {
+		t.Fatalf("unexpected error for a zero Smoothing Pool address: %s", err.Error())
+	}
+}
+
+func TestGenesisTimeOverrideReachesEndTime(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.Uint64("genesis-time", 0, "")
+	set.Bool("allow-experimental-networks", false, "")
+	if err := set.Parse([]string{"--genesis-time", "1000", "--allow-experimental-networks"}); err != nil {
+		t.Fatalf("error parsing flags: %s", err.Error())
+	}
+	c := cli.NewContext(nil, set, nil)
+
+	beaconConfig := beacon.Eth2Config{GenesisTime: 1, SecondsPerSlot: 12, SlotsPerEpoch: 32}
+	logger := log.NewColorLogger(color.FgHiWhite)
+	if err := overrideBeaconConfig(c, &beaconConfig, &logger); err != nil {
+		t.Fatalf("error overriding the beacon config: %s", err.Error())
+	}
+	g := &treeGenerator{
+		beaconConfig: beaconConfig,
+		targets:      targets{block: &beacon.BeaconBlock{Slot: 100}},
+	}
+	if endTime := g.getSnapshotEndTime(); !endTime.Equal(time.Unix(1000+100*12, 0)) {
+		t.Fatalf("expected the end time to use the overridden genesis time, got %s", endTime)
+	}
+}
+
+func TestBeaconConfigOverrideRuleset(t *testing.T) {
+	for ruleset := uint64(1); ruleset <= 7; ruleset++ {
+		err := checkBeaconConfigOverrideRuleset(ruleset)
+		if ruleset < 5 && err == nil {
+			t.Fatalf("expected ruleset v%d to be rejected with a beacon config override", ruleset)
+		}
+		if ruleset >= 5 && err != nil {
+			t.Fatalf("expected ruleset v%d to be allowed with a beacon config override, got %s", ruleset, err.Error())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/fatih/color"
+	rprewards "github.com/rocket-pool/smartnode/shared/services/rewards"
+	"github.com/rocket-pool/smartnode/shared/utils/log"
+	"github.com/urfave/cli/v2"
+	"github.com/wealdtech/go-merkletree"
+	"github.com/wealdtech/go-merkletree/keccak256"
+)
+
+// Rebuilds the Merkle tree of a rewards file from its node entries and checks it against the embedded root and proofs
+func ValidateFile(c *cli.Context) error {
+	logger := log.NewColorLogger(color.FgHiWhite)
+	errLogger := log.NewColorLogger(color.FgRed)
+	path := c.String("validate-file")
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	rewardsFile, err := rprewards.DeserializeRewardsFile(bytes)
+	if err != nil {
+		return fmt.Errorf("error deserializing %s: %w", path, err)
+	}
+	header := rewardsFile.GetHeader()
+	logger.Printlnf("Loaded rewards file v%d for interval %d on %s with %d nodes", header.RewardsFileVersion, header.Index, header.Network, len(rewardsFile.GetNodeAddresses()))
+
+	// Rebuild the leaves the same way the generator does
+	leaves := map[common.Address][]byte{}
+	totalData := make([][]byte, 0, len(rewardsFile.GetNodeAddresses()))
+	for _, address := range rewardsFile.GetNodeAddresses() {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		leaf := merkleLeaf(address, info)
+		if leaf == nil {
+			continue
+		}
+		leaves[address] = leaf
+		totalData = append(totalData, leaf)
+	}
+	if len(totalData) == 0 {
+		return fmt.Errorf("%s has no nodes with rewards", path)
+	}
+	tree, err := merkletree.NewUsing(totalData, keccak256.New(), false, true)
+	if err != nil {
+		return fmt.Errorf("error generating Merkle tree: %w", err)
+	}
+
+	// Every node's embedded proof should match the one from the rebuilt tree
+	mismatches := 0
+	for address, leaf := range leaves {
+		info, _ := rewardsFile.GetNodeRewardsInfo(address)
+		embedded, err := info.GetMerkleProof()
+		if err != nil {
+			errLogger.Printlnf("Node %s has an unreadable Merkle proof: %s", address.Hex(), err.Error())
+			mismatches++
+			continue
+		}
+		proof, err := tree.GenerateProof(leaf, 0)
+		if err != nil {
+			return fmt.Errorf("error generating proof for node %s: %w", address.Hex(), err)
+		}
+		if !proofsEqual(embedded, proof.Hashes) {
+			errLogger.Printlnf("Node %s has a Merkle proof that doesn't match the rebuilt tree", address.Hex())
+			mismatches++
+		}
+	}
+
+	root := common.BytesToHash(tree.Root())
+	embeddedRoot := common.HexToHash(header.MerkleRoot)
+	if root != embeddedRoot {
+		errLogger.Printlnf("The rebuilt Merkle root is %s, but the file claims %s", root.Hex(), header.MerkleRoot)
+		return fmt.Errorf("%s failed validation: Merkle root mismatch, %d mismatched proof(s)", path, mismatches)
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%s failed validation: %d mismatched proof(s)", path, mismatches)
+	}
+
+	logger.Printlnf("The rebuilt Merkle root matches the file's root of %s, and all %d proofs are valid.", root.Hex(), len(leaves))
+	return nil
+}
+
+// Gets a node's Merkle leaf: address[20] :: network[32] :: RPL[32] :: ETH[32].
+// Nodes without any rewards aren't part of the tree, so they have no leaf.
+func merkleLeaf(address common.Address, info rprewards.INodeRewardsInfo) []byte {
+	rpl := big.NewInt(0).Add(&info.GetCollateralRpl().Int, &info.GetOracleDaoRpl().Int)
+	eth := &info.GetSmoothingPoolEth().Int
+	if rpl.Sign() == 0 && eth.Sign() == 0 {
+		return nil
+	}
+
+	leaf := make([]byte, 0, 20+32*3)
+	leaf = append(leaf, address.Bytes()...)
+	leaf = append(leaf, common.BigToHash(big.NewInt(0).SetUint64(info.GetRewardNetwork())).Bytes()...)
+	leaf = append(leaf, common.BigToHash(rpl).Bytes()...)
+	leaf = append(leaf, common.BigToHash(eth).Bytes()...)
+	return leaf
+}
+
+// Checks whether an embedded proof is the same as a generated one
+func proofsEqual(embedded []common.Hash, generated [][]byte) bool {
+	if len(embedded) != len(generated) {
+		return false
+	}
+	for i, hash := range embedded {
+		if hash != common.BytesToHash(generated[i]) {
+			return false
+		}
+	}
+	return true
+}
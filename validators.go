@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/rocket-pool/rocketpool-go/types"
+	"github.com/rocket-pool/rocketpool-go/utils/eth"
+	"github.com/rocket-pool/smartnode/shared/services/beacon"
+	"github.com/rocket-pool/smartnode/shared/services/state"
+)
+
+// Reports the Rocket Pool minipool validators that are slashed or exiting / exited at the snapshot,
+// since these are common causes of unexpected reward changes. The totals are always logged; the
+// individual validators are only listed in verbose mode.
+func (g *treeGenerator) logValidatorIssues(networkState *state.NetworkState) {
+	slashed := 0
+	exited := 0
+	for _, mpd := range networkState.MinipoolDetails {
+		validator, exists := networkState.ValidatorDetails[mpd.Pubkey]
+		if !exists {
+			continue
+		}
+
+		switch {
+		case validator.Slashed:
+			slashed++
+			if g.verbose {
+				g.log.Printlnf("slashed validator: index=%s pubkey=%s status=%s minipool=%s node=%s", validator.Index, mpd.Pubkey.Hex(), validator.Status, mpd.MinipoolAddress.Hex(), mpd.NodeAddress.Hex())
+			}
+		case validator.Status == beacon.ValidatorState_ActiveExiting ||
+			validator.Status == beacon.ValidatorState_ExitedUnslashed ||
+			validator.Status == beacon.ValidatorState_WithdrawalPossible ||
+			validator.Status == beacon.ValidatorState_WithdrawalDone:
+			exited++
+			if g.verbose {
+				g.log.Printlnf("exited validator: index=%s pubkey=%s status=%s minipool=%s node=%s", validator.Index, mpd.Pubkey.Hex(), validator.Status, mpd.MinipoolAddress.Hex(), mpd.NodeAddress.Hex())
+			}
+		}
+	}
+
+	if slashed+exited > 0 {
+		g.warn(warningValidatorIssues, "found %d slashed and %d exiting / exited Rocket Pool validators at slot %d.", slashed, exited, networkState.BeaconSlotNumber)
+	} else {
+		g.log.Printlnf("No slashed or exiting / exited Rocket Pool validators at slot %d.", networkState.BeaconSlotNumber)
+	}
+}
+
+// Logs how many minipools are staking at the snapshot, broken down by the node operator's bond
+func (g *treeGenerator) logMinipoolSummary(networkState *state.NetworkState) {
+	total := 0
+	bonds := map[float64]int{}
+	for _, mpd := range networkState.MinipoolDetails {
+		if mpd.Status != types.Staking || mpd.Finalised {
+			continue
+		}
+		total++
+		bonds[eth.WeiToEth(mpd.NodeDepositBalance)]++
+	}
+
+	amounts := make([]float64, 0, len(bonds))
+	for amount := range bonds {
+		amounts = append(amounts, amount)
+	}
+	sort.Float64s(amounts)
+
+	g.log.Printlnf("Staking minipools at slot %d: %d", networkState.BeaconSlotNumber, total)
+	for _, amount := range amounts {
+		g.log.Printlnf("    %g ETH bond: %d", amount, bonds[amount])
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+
+// A stable identifier for a kind of warning, so log processors can alert on it without matching the message
+type warningCode string
+
+const (
+	warningOptimisticBn         warningCode = "OPTIMISTIC_BN"
+	warningOptimisticUnchecked  warningCode = "OPTIMISTIC_UNCHECKED"
+	warningInvalidProof         warningCode = "INVALID_PROOF"
+	warningStartTimeOverride    warningCode = "START_TIME_OVERRIDE"
+	warningIntervalTimeOverride warningCode = "INTERVAL_TIME_OVERRIDE"
+	warningResumeIgnored        warningCode = "RESUME_IGNORED"
+	warningInvalidNetworkNode   warningCode = "INVALID_NETWORK_NODE"
+	warningRootUnchecked        warningCode = "ROOT_UNCHECKED"
+	warningRootMismatch         warningCode = "ROOT_MISMATCH"
+	warningNodeFilter           warningCode = "NODE_FILTER"
+	warningZeroRewardsOmitted   warningCode = "ZERO_REWARDS_OMITTED"
+	warningAnonymized           warningCode = "ANONYMIZED"
+	warningNoProofs             warningCode = "NO_PROOFS"
+	warningValidatorIssues      warningCode = "VALIDATOR_ISSUES"
+	warningElTimeMismatch       warningCode = "EL_TIME_MISMATCH"
+	warningHypotheticalWindow   warningCode = "HYPOTHETICAL_WINDOW"
+	warningDuplicateNodes       warningCode = "DUPLICATE_NODES"
+)
+
+// A warning raised during the run
+type runWarning struct {
+	Code    warningCode `json:"code"`
+	Message string      `json:"message"`
+}
+
+// Logs a warning and keeps it for the summary printed at the end of the run
+func (g *treeGenerator) warn(code warningCode, format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	g.warnings = append(g.warnings, runWarning{Code: code, Message: message})
+	g.log.Printlnf("WARNING [%s]: %s", code, message)
+}
+
+// Prints every warning raised during the run so they aren't lost in a long log
+func (g *treeGenerator) printWarnings() {
+	if len(g.warnings) == 0 {
+		return
+	}
+
+	g.log.Println()
+	g.log.Printlnf("=== %d Warning(s) ===", len(g.warnings))
+	for _, warning := range g.warnings {
+		g.log.Printlnf("- [%s] %s", warning.Code, warning.Message)
+	}
+}
+
+// Saves every warning raised during the run as a JSON array of codes and messages, empty if there were none
+func (g *treeGenerator) writeWarnings(path string) error {
+	warnings := g.warnings
+	if warnings == nil {
+		warnings = []runWarning{}
+	}
+	bytes, err := g.serializeJson(warnings)
+	if err != nil {
+		return fmt.Errorf("error serializing warnings into JSON: %w", err)
+	}
+	err = writeFileAtomic(path, bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error saving warnings to %s: %w", path, err)
+	}
+	return nil
+}
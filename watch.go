@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rocket-pool/rocketpool-go/rewards"
+)
+
+// Polls the reward index and generates the tree for each interval as its submission event appears,
+// until the process is interrupted
+func (g *treeGenerator) watch(pollInterval time.Duration) error {
+	// Intervals before the current index have already been submitted, so start watching from here
+	index, err := rewards.GetRewardIndex(g.rp, nil)
+	if err != nil {
+		return fmt.Errorf("error getting current reward index: %w", err)
+	}
+	nextInterval := index.Uint64()
+	g.log.Printlnf("Watching for the submission of interval %d (polling every %s)...", nextInterval, pollInterval)
+
+	for {
+		if waitForPoll(pollInterval) {
+			g.log.Println("Interrupted, no longer watching for new intervals.")
+			return nil
+		}
+
+		index, err := rewards.GetRewardIndex(g.rp, nil)
+		if err != nil {
+			g.errLog.Printlnf("Error getting current reward index: %s", err.Error())
+			continue
+		}
+
+		// Generate every interval that was submitted since the last poll
+		for ; nextInterval < index.Uint64(); nextInterval++ {
+			g.log.Printlnf("Detected the submission of interval %d, generating its tree.", nextInterval)
+			err := g.generateWatchedInterval(nextInterval)
+			if err == nil {
+				continue
+			}
+
+			// EC and BN errors are retried on the next poll so a flaky call doesn't cost an interval. Anything else,
+			// like a root mismatch, would fail the same way every time and hold up every later interval, so it's skipped.
+			if isEndpointError(err) {
+				g.errLog.Printlnf("Error generating interval %d, retrying on the next poll: %s", nextInterval, err.Error())
+				break
+			}
+			g.errLog.Printlnf("Error generating interval %d, skipping it: %s", nextInterval, err.Error())
+		}
+	}
+}
+
+// Sets up and generates one interval's tree, starting from a clean slate
+func (g *treeGenerator) generateWatchedInterval(index uint64) error {
+	g.targets = targets{}
+	g.recordMgr = nil
+	g.warnings = nil
+	if err := g.setTargets(int64(index), 0); err != nil {
+		return fmt.Errorf("error setting the targets for interval %d: %w", index, err)
+	}
+	return g.generateTree()
+}
+
+// Waits for the next poll, returning true if the process was interrupted first.
+// Signals are only caught while waiting, so an interrupt during a generation stops the process right away instead of
+// being held until every pending interval is done; the files are written atomically, so none are left half-written.
+func waitForPoll(pollInterval time.Duration) bool {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	select {
+	case <-interrupt:
+		return true
+	case <-time.After(pollInterval):
+		return false
+	}
+}